@@ -0,0 +1,71 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+// doctor returns a doctor with the given number of checks, one of them failing
+func doctor(b *testing.B, checks int) *Doctor {
+	b.Helper()
+	health := NewDoctor()
+	b.Cleanup(health.Stop)
+	for i := 0; i < checks; i++ {
+		name := fmt.Sprintf("check-%d", i)
+		failing := i == checks-1
+		err := health.Investigate(&Check{
+			Name:     name,
+			Interval: time.Hour,
+			Handler: func(context.Context) error {
+				if failing {
+					return errors.New("down")
+				}
+				return nil
+			},
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		health.RunCheck(context.Background(), name)
+	}
+	return health
+}
+
+func BenchmarkHealthy(b *testing.B) {
+	health := doctor(b, 1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		health.Healthy()
+	}
+}
+
+// fakeListener hands out the same connection on every Accept, so the benchmark measures the
+// health listener rather than the network
+type fakeListener struct {
+	net.Listener
+	conn net.Conn
+}
+
+func (l fakeListener) Accept() (net.Conn, error) {
+	return l.conn, nil
+}
+
+type fakeConn struct{ net.Conn }
+
+func (fakeConn) Close() error { return nil }
+
+func BenchmarkAccept(b *testing.B) {
+	ln := NewListener(fakeListener{conn: fakeConn{}}, doctor(b, 1000))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ln.Accept(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package doctor
+
+import "time"
+
+// The states of the circuit breaker of a check, see Check.BreakerThreshold
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// tripped tells whether the breaker of the check is open, so the scheduled probe is left out.
+// Once the cooldown passed, the breaker turns half-open and lets a single trial probe through.
+func (hc *healthCheckStatus) tripped(now time.Time) bool {
+	hc.Lock()
+	defer hc.Unlock()
+	if hc.breaker != BreakerOpen {
+		return false
+	}
+	if now.Sub(hc.opened) < hc.BreakerCooldown {
+		return true
+	}
+	hc.breaker = BreakerHalfOpen
+	return false
+}
+
+// trip records a result in the breaker of the check, the check must be locked. A failed trial
+// probe opens the breaker again right away.
+func (hc *healthCheckStatus) trip(failed bool, at time.Time) {
+	switch {
+	case hc.BreakerThreshold <= 0:
+	case !failed:
+		hc.breaker = BreakerClosed
+	case hc.breaker == BreakerHalfOpen || hc.failures >= hc.BreakerThreshold:
+		hc.breaker, hc.opened = BreakerOpen, at
+	}
+}
@@ -0,0 +1,66 @@
+package doctor_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/decoomanj/doctor"
+	"github.com/decoomanj/doctor/doctortest"
+)
+
+func TestBreakerOpensAndProbesOnceAfterCooldown(t *testing.T) {
+	d := doctortest.New()
+	defer d.Stop()
+	down := true
+	err := d.Investigate(&doctor.Check{
+		Name:             "db",
+		Interval:         10 * time.Second,
+		Timeout:          time.Second,
+		BreakerThreshold: 2,
+		BreakerCooldown:  30 * time.Second,
+		Handler: func(context.Context) error {
+			if down {
+				return errors.New("down")
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, step := range []struct {
+		at      int
+		recover bool
+		probed  bool
+		breaker string
+	}{
+		{0, false, true, doctor.BreakerClosed},
+		{10, false, true, doctor.BreakerOpen},
+		{20, false, false, doctor.BreakerOpen},
+		{30, false, false, doctor.BreakerOpen},
+		// the cooldown passed, the failed trial opens the breaker again right away
+		{40, false, true, doctor.BreakerOpen},
+		{50, false, false, doctor.BreakerOpen},
+		{60, false, false, doctor.BreakerOpen},
+		// a successful trial closes it
+		{70, true, true, doctor.BreakerClosed},
+		{80, true, true, doctor.BreakerClosed},
+	} {
+		by := 10 * time.Second
+		if step.at == 0 {
+			by = 0
+		}
+		down = !step.recover
+		_, probed := d.Tick(context.Background(), by)["db"]
+		status := d.Status()[0]
+		if probed != step.probed || status.Breaker != step.breaker {
+			t.Fatalf("at %ds: probed %t with breaker %s, want %t with %s", step.at, probed, status.Breaker, step.probed, step.breaker)
+		}
+	}
+	if !d.Healthy() {
+		t.Fatal("unhealthy after the breaker closed")
+	}
+}
@@ -0,0 +1,28 @@
+package doctor
+
+import "errors"
+
+// receive applies the results of the channel until the check is done. When the channel is
+// closed, the check is removed or marked failing, depending on RemoveOnClose.
+func (hc *healthCheckStatus) receive(health *Doctor) {
+	for {
+		select {
+		case <-hc.ctx.Done():
+			return
+		case err, ok := <-hc.Results:
+			if ok {
+				hc.apply(health, hc.next(), err, health.clock.Now(), 0, "")
+				continue
+			}
+			if hc.RemoveOnClose {
+				// the name may be registered again by now, only remove this check
+				if health.remove(hc.Name, hc) == nil {
+					health.aggregate()
+				}
+				return
+			}
+			hc.apply(health, hc.next(), errors.New("result channel closed"), health.clock.Now(), 0, "")
+			return
+		}
+	}
+}
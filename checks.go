@@ -0,0 +1,160 @@
+package doctor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TCPCheck creates a check which succeeds when a TCP connection to the address can be opened
+func TCPCheck(name, addr string) *Check {
+	return &Check{
+		Name: name,
+		Handler: func(ctx context.Context) error {
+			var dialer net.Dialer
+			conn, err := dialer.DialContext(ctx, "tcp", addr)
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		},
+	}
+}
+
+// HTTPOption configures the check of HTTPCheck
+type HTTPOption func(*httpCheck)
+
+// httpCheck holds the configuration of an HTTP check
+type httpCheck struct {
+	statuses  map[int]bool
+	redirects bool
+	body      string
+	header    http.Header
+}
+
+// want returns the accepted status codes, sorted, e.g. "200" or "one of 200, 204"
+func (c *httpCheck) want() string {
+	codes := make([]int, 0, len(c.statuses))
+	for code := range c.statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	list := make([]string, len(codes))
+	for i, code := range codes {
+		list[i] = strconv.Itoa(code)
+	}
+	if len(list) == 1 {
+		return list[0]
+	}
+	return "one of " + strings.Join(list, ", ")
+}
+
+// maxBody bounds the part of the response body which is searched for the expected substring
+const maxBody = 1 << 20
+
+// WithStatusCodes accepts the given status codes as well as the expected one
+func WithStatusCodes(codes ...int) HTTPOption {
+	return func(c *httpCheck) {
+		for _, code := range codes {
+			c.statuses[code] = true
+		}
+	}
+}
+
+// WithFollowRedirects sets whether redirects are followed, which they are by default. Without,
+// the status of the redirect itself is checked, so a redirect to a login page fails the check.
+func WithFollowRedirects(follow bool) HTTPOption {
+	return func(c *httpCheck) {
+		c.redirects = follow
+	}
+}
+
+// WithBodyContains requires the response body to contain the substring. Only the first MiB of
+// the body is searched.
+func WithBodyContains(substr string) HTTPOption {
+	return func(c *httpCheck) {
+		c.body = substr
+	}
+}
+
+// WithHeader adds a header to the request, e.g. for authorization. A Host header sets the host
+// of the request, e.g. for a virtual host behind the address.
+func WithHeader(key, value string) HTTPOption {
+	return func(c *httpCheck) {
+		c.header.Add(key, value)
+	}
+}
+
+// HTTPCheck creates a check which succeeds when a GET on the url responds with the expected
+// status code. The request is bound by the timeout of the check.
+func HTTPCheck(name, url string, expectStatus int, opts ...HTTPOption) *Check {
+	config := &httpCheck{
+		statuses:  map[int]bool{expectStatus: true},
+		redirects: true,
+		header:    make(http.Header),
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+	client := http.DefaultClient
+	if !config.redirects {
+		client = &http.Client{
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+
+	return &Check{
+		Name: name,
+		Handler: func(ctx context.Context) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return err
+			}
+			for key, values := range config.header {
+				req.Header[key] = values
+			}
+			if host := config.header.Get("Host"); host != "" {
+				// the client sends the host of the request, not the header
+				req.Host = host
+				req.Header.Del("Host")
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			if !config.statuses[resp.StatusCode] {
+				return fmt.Errorf("unexpected status %d, want %s", resp.StatusCode, config.want())
+			}
+			if config.body == "" {
+				return nil
+			}
+			body, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+			if err != nil {
+				return err
+			}
+			if !strings.Contains(string(body), config.body) {
+				return fmt.Errorf("response body does not contain %q", config.body)
+			}
+			return nil
+		},
+	}
+}
+
+// PingCheck creates a check which succeeds when the database can be pinged
+func PingCheck(name string, db *sql.DB) *Check {
+	return &Check{
+		Name: name,
+		Handler: func(ctx context.Context) error {
+			return db.PingContext(ctx)
+		},
+	}
+}
@@ -0,0 +1,41 @@
+package doctor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPCheckSendsHostHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "api.internal" || r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusMisdirectedRequest)
+		}
+	}))
+	defer server.Close()
+	check := HTTPCheck("api", server.URL, http.StatusOK, WithHeader("host", "api.internal"), WithHeader("X-Token", "secret"))
+	if err := check.Handler(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTTPCheckListsAcceptedStatuses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer server.Close()
+	for _, c := range []struct {
+		check *Check
+		want  string
+	}{
+		{HTTPCheck("api", server.URL, http.StatusOK), "unexpected status 418, want 200"},
+		{HTTPCheck("api", server.URL, http.StatusOK, WithStatusCodes(http.StatusNoContent, http.StatusAccepted)), "unexpected status 418, want one of 200, 202, 204"},
+	} {
+		err := c.check.Handler(context.Background())
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Fatalf("error %v, want %q", err, c.want)
+		}
+	}
+}
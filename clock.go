@@ -0,0 +1,59 @@
+package doctor
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is the source of time of the doctor. It schedules the probes and the expiry of push
+// checks, bounds the probes by their timeout and stamps the results. Replace it with a fake to
+// step a doctor in tests, see the doctortest package.
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+
+	// After returns a channel which receives the time once the duration has passed
+	After(d time.Duration) <-chan time.Time
+
+	// WithTimeout returns a copy of the context which is done once the duration has passed, with
+	// context.DeadlineExceeded as error
+	WithTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc)
+}
+
+// sleeper is a clock which passes a wait by itself rather than waiting for the time to pass,
+// e.g. the fake clock of doctortest advances by the duration
+type sleeper interface {
+	Sleep(d time.Duration)
+}
+
+// sleep waits for the duration on the clock and returns false when the context is done first.
+// Without scheduler, on a clock which is a sleeper, the clock passes the wait itself, so a
+// synchronous Step cannot wait for a time which nobody advances.
+func (health *Doctor) sleep(ctx context.Context, d time.Duration) bool {
+	if s, ok := health.clock.(sleeper); ok && health.manual {
+		s.Sleep(d)
+		return ctx.Err() == nil
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-health.clock.After(d):
+		return true
+	}
+}
+
+// realClock is the wall clock. It is an empty struct, so it costs nothing over calling the time
+// package directly.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) WithTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, d)
+}
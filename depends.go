@@ -0,0 +1,72 @@
+package doctor
+
+import "fmt"
+
+// blocking returns the name of an unhealthy or skipped dependency of the check, if any.
+// Dependencies which are not registered are ignored.
+func (checks *healthChecks) blocking(hc *healthCheckStatus) string {
+	checks.RLock()
+	defer checks.RUnlock()
+	for _, name := range hc.DependsOn {
+		dependency, ok := checks.items[name]
+		if !ok {
+			continue
+		}
+		dependency.RLock()
+		blocked := dependency.failing() || dependency.skipped
+		dependency.RUnlock()
+		if blocked {
+			return name
+		}
+	}
+	return ""
+}
+
+// cyclic tells whether registering a check with the given dependencies closes a cycle, taking
+// the dependencies of pending checks into account as well. The checks must be locked.
+func (checks *healthChecks) cyclic(name string, dependsOn []string, pending map[string][]string) bool {
+	visited := make(map[string]bool)
+	var reaches func(from string) bool
+	reaches = func(from string) bool {
+		if from == name {
+			return true
+		}
+		if visited[from] {
+			return false
+		}
+		visited[from] = true
+		next, ok := pending[from]
+		if hc, registered := checks.items[from]; !ok && registered {
+			next = hc.DependsOn
+		}
+		for _, dependency := range next {
+			if reaches(dependency) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, dependency := range dependsOn {
+		if reaches(dependency) {
+			return true
+		}
+	}
+	return false
+}
+
+// skip marks the check as skipped because of an unhealthy dependency
+func (hc *healthCheckStatus) skip(health *Doctor, dependency string) {
+	hc.Lock()
+	if hc.ctx.Err() != nil {
+		// removed meanwhile, its position may belong to another check already
+		hc.Unlock()
+		return
+	}
+	hc.skipped = true
+	hc.msg = fmt.Sprintf("skipped: dependency %q is unhealthy", dependency)
+	hc.bits.update(hc.pos, true)
+	hc.Unlock()
+
+	hc.regroup(health)
+	health.aggregate()
+}
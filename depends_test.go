@@ -0,0 +1,113 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSyncFirstProbeSchedulesSkippedChecksRightAway(t *testing.T) {
+	health := NewDoctor(WithSyncFirstProbe(), WithoutScheduler())
+	t.Cleanup(health.Stop)
+	down := true
+	err := health.InvestigateAll(context.Background(), &Check{
+		Name:      "migrations",
+		Interval:  time.Hour,
+		DependsOn: []string{"db"},
+		Handler: func(context.Context) error {
+			return nil
+		},
+	}, &Check{
+		Name:     "db",
+		Interval: time.Hour,
+		Handler: func(context.Context) error {
+			if down {
+				return errors.New("down")
+			}
+			return nil
+		},
+	}, &Check{
+		Name:      "seed",
+		Interval:  time.Hour,
+		DependsOn: []string{"migrations"},
+		Handler: func(context.Context) error {
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range health.Status() {
+		if status.Name != "db" && (!status.Skipped || status.Healthy) {
+			t.Fatalf("%s not skipped behind the failing db: %+v", status.Name, status)
+		}
+	}
+
+	// the skipped checks did not run their first probe, they are due right away rather than an
+	// interval later
+	down = false
+	if err := health.RunCheck(context.Background(), "db"); err != nil {
+		t.Fatal(err)
+	}
+	results := health.Step(context.Background())
+	for _, name := range []string{"migrations", "seed"} {
+		if err, ok := results[name]; !ok || err != nil {
+			t.Fatalf("%s: result %v, ran %t", name, err, ok)
+		}
+	}
+	if !health.Healthy() {
+		t.Fatal("unhealthy once the db recovered")
+	}
+}
+
+func TestSyncFirstProbeRunsDependenciesFirst(t *testing.T) {
+	health := NewDoctor(WithSyncFirstProbe(), WithoutScheduler())
+	t.Cleanup(health.Stop)
+	checks := []*Check{{Name: "migrations", DependsOn: []string{"db"}}, {Name: "db"}, {Name: "app", DependsOn: []string{"db", "migrations"}}}
+	for _, check := range checks {
+		check.Interval = time.Hour
+		check.Handler = func(context.Context) error {
+			return nil
+		}
+	}
+	if err := health.InvestigateAll(context.Background(), checks...); err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range health.Status() {
+		if !status.Healthy || status.Skipped {
+			t.Fatalf("%s: %+v", status.Name, status)
+		}
+	}
+}
+
+func TestSkipOfRemovedCheckKeepsStatus(t *testing.T) {
+	health := NewDoctor(WithoutScheduler())
+	t.Cleanup(health.Stop)
+	check := func(name string) *Check {
+		return &Check{
+			Name:     name,
+			Interval: time.Hour,
+			Handler: func(context.Context) error {
+				return errors.New("down")
+			},
+		}
+	}
+	if err := health.Investigate(check("old")); err != nil {
+		t.Fatal(err)
+	}
+	old := health.checks.items["old"]
+	if err := health.Remove("old"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the new check takes the freed position and fails
+	if err := health.Investigate(check("new")); err != nil {
+		t.Fatal(err)
+	}
+	health.RunCheck(context.Background(), "new")
+	old.skip(health, "db")
+	if health.Healthy() {
+		t.Fatalf("a stale skip cleared the bit of the failing check, bits %v", health.StatusBits())
+	}
+}
@@ -0,0 +1,49 @@
+package doctor
+
+import "fmt"
+
+// Disable pauses a check, e.g. during planned maintenance of a dependency. A disabled check is
+// not probed and counts as healthy, but stays registered and shows as disabled in the status.
+func (health *Doctor) Disable(name string) error {
+	return health.toggle(name, true)
+}
+
+// Enable resumes a disabled check. It reports its last known state until the next probe, which
+// runs right away.
+func (health *Doctor) Enable(name string) error {
+	return health.toggle(name, false)
+}
+
+// toggle the disabled state of a check
+func (health *Doctor) toggle(name string, disabled bool) error {
+	health.checks.RLock()
+	check, ok := health.checks.items[name]
+	health.checks.RUnlock()
+	if !ok {
+		return fmt.Errorf("health-check %q not found", name)
+	}
+
+	check.Lock()
+	if check.ctx.Err() != nil {
+		// removed meanwhile, its position may belong to another check already
+		check.Unlock()
+		return fmt.Errorf("health-check %q not found", name)
+	}
+	check.disabled = disabled
+	check.generation++ // discard a probe in flight
+	check.bits.update(check.pos, !check.failing())
+	check.Unlock()
+
+	if !disabled {
+		// wake the loop, whether it waits for the check to be enabled or for its next probe
+		select {
+		case check.resume <- struct{}{}:
+		default:
+		}
+	}
+	if check.group != nil {
+		check.group.refresh(health.status)
+	}
+	health.aggregate()
+	return nil
+}
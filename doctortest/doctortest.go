@@ -0,0 +1,164 @@
+// Package doctortest provides a doctor for tests, which only probes when told so.
+package doctortest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/decoomanj/doctor"
+)
+
+type (
+	// Doctor is a doctor on a fake clock without probe loops, so nothing is probed behind the
+	// back of the test: Tick moves the clock and probes the checks which are due synchronously.
+	Doctor struct {
+		*doctor.Doctor
+
+		// The fake clock of the doctor
+		Clock *Clock
+	}
+
+	// Clock is a fake clock which only moves on Advance
+	Clock struct {
+		sync.Mutex
+		now    time.Time
+		timers []*timer
+	}
+
+	// timer is pending on the fake clock, it fires once the clock reaches its time
+	timer struct {
+		at   time.Time
+		fire func(time.Time)
+	}
+
+	// timeout is a context which times out on the fake clock
+	timeout struct {
+		context.Context
+		sync.Mutex
+		deadline time.Time
+		expired  bool
+	}
+)
+
+// New creates a doctor on a fake clock, without scheduler. The options are applied on top of
+// both, so they must not replace the clock.
+func New(opts ...doctor.Option) *Doctor {
+	clock := NewClock(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	return &Doctor{
+		Doctor: doctor.NewDoctor(append([]doctor.Option{doctor.WithClock(clock), doctor.WithoutScheduler()}, opts...)...),
+		Clock:  clock,
+	}
+}
+
+// Tick advances the clock and probes the checks which are due then, synchronously, sorted by
+// name, as the scheduler would. It returns the results of the probes by name, so a test of
+// intervals, backoff or jitter sees exactly which checks ran. A tick of zero probes the checks
+// which are due already, e.g. right after registration. Push checks are left out, they report
+// with Heartbeat and expire with the clock. A handler which waits for its timeout blocks the
+// tick, the fake timeout only passes with Advance. The retry delays of a failing probe advance
+// the clock, as if the time passed during the tick.
+func (d *Doctor) Tick(ctx context.Context, by time.Duration) map[string]error {
+	d.Clock.Advance(by)
+	return d.Step(ctx)
+}
+
+// NewClock creates a fake clock at the given time
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the time of the fake clock
+func (c *Clock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+// After returns a channel which receives the time once the clock is advanced to or beyond the
+// duration. It never fires without an Advance, not even for a duration of zero.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.Lock()
+	defer c.Unlock()
+	ch := make(chan time.Time, 1)
+	c.timers = append(c.timers, &timer{at: c.now.Add(d), fire: func(now time.Time) {
+		ch <- now
+	}})
+	return ch
+}
+
+// WithTimeout returns a copy of the context which times out once the clock is advanced to or
+// beyond the duration. A deadline of the parent is ignored, only the fake time counts. Canceling
+// the context drops its timer.
+func (c *Clock) WithTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	c.Lock()
+	defer c.Unlock()
+	parent, cancel := context.WithCancel(ctx)
+	sub := &timeout{Context: parent, deadline: c.now.Add(d)}
+	t := &timer{at: sub.deadline, fire: func(time.Time) {
+		sub.expire()
+		cancel()
+	}}
+	c.timers = append(c.timers, t)
+	return sub, func() {
+		cancel()
+		c.drop(t)
+	}
+}
+
+// drop a pending timer
+func (c *Clock) drop(t *timer) {
+	c.Lock()
+	defer c.Unlock()
+	for i, pending := range c.timers {
+		if pending == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Sleep advances the clock by the duration. Without scheduler, the doctor passes the retry
+// delays of the probes with it, so Tick does not wait for an Advance which cannot come.
+func (c *Clock) Sleep(d time.Duration) {
+	c.Advance(d)
+}
+
+// Advance moves the clock forward and fires the timers which are due. The probe loops woken by
+// them run asynchronously, as on a real clock.
+func (c *Clock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.fire(c.now)
+	}
+	c.timers = pending
+}
+
+// Deadline returns the fake deadline
+func (t *timeout) Deadline() (time.Time, bool) {
+	return t.deadline, true
+}
+
+// Err returns context.DeadlineExceeded once the context timed out
+func (t *timeout) Err() error {
+	t.Lock()
+	defer t.Unlock()
+	if t.expired {
+		return context.DeadlineExceeded
+	}
+	return t.Context.Err()
+}
+
+// expire the context, unless it is done already
+func (t *timeout) expire() {
+	t.Lock()
+	defer t.Unlock()
+	t.expired = t.Context.Err() == nil
+}
@@ -0,0 +1,232 @@
+package doctortest
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/decoomanj/doctor"
+)
+
+// ticks advances the doctor second by second for the given duration and returns the seconds
+// at which the check was probed
+func ticks(d *Doctor, name string, total time.Duration) []int {
+	var at []int
+	for elapsed := time.Duration(0); elapsed <= total; elapsed += time.Second {
+		by := time.Second
+		if elapsed == 0 {
+			by = 0
+		}
+		if _, ok := d.Tick(context.Background(), by)[name]; ok {
+			at = append(at, int(elapsed/time.Second))
+		}
+	}
+	return at
+}
+
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAdvanceFiresDueTimers(t *testing.T) {
+	clock := NewClock(time.Unix(0, 0))
+	now, later := clock.After(0), clock.After(5*time.Second)
+	select {
+	case <-now:
+		t.Fatal("timer fired without Advance")
+	default:
+	}
+
+	clock.Advance(4 * time.Second)
+	select {
+	case <-now:
+	default:
+		t.Fatal("due timer did not fire")
+	}
+	select {
+	case <-later:
+		t.Fatal("timer fired early")
+	default:
+	}
+
+	clock.Advance(time.Second)
+	select {
+	case at := <-later:
+		if !at.Equal(time.Unix(5, 0)) {
+			t.Fatalf("fired at %s", at)
+		}
+	default:
+		t.Fatal("timer did not fire at its time")
+	}
+}
+
+func TestWithTimeoutExpiresOnAdvance(t *testing.T) {
+	clock := NewClock(time.Unix(0, 0))
+	ctx, cancel := clock.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if deadline, _ := ctx.Deadline(); !deadline.Equal(time.Unix(1, 0)) {
+		t.Fatalf("deadline %s", deadline)
+	}
+	clock.Advance(time.Second)
+	<-ctx.Done()
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Fatalf("error %v, want deadline exceeded", ctx.Err())
+	}
+
+	_, cancel = clock.WithTimeout(context.Background(), time.Second)
+	cancel()
+	if n := len(clock.timers); n != 0 {
+		t.Fatalf("%d timers left after cancel", n)
+	}
+}
+
+func TestTickProbesDueChecks(t *testing.T) {
+	d := New()
+	defer d.Stop()
+	for name, interval := range map[string]time.Duration{"a": 10 * time.Second, "b": 15 * time.Second} {
+		err := d.Investigate(&doctor.Check{
+			Name:     name,
+			Interval: interval,
+			Timeout:  time.Second,
+			Handler: func(context.Context) error {
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, step := range []struct {
+		by   time.Duration
+		want []string
+	}{
+		{0, []string{"a", "b"}},
+		{0, nil},
+		{9 * time.Second, nil},
+		{time.Second, []string{"a"}},
+		{5 * time.Second, []string{"b"}},
+		{5 * time.Second, []string{"a"}},
+		{10 * time.Second, []string{"a", "b"}},
+	} {
+		results := d.Tick(context.Background(), step.by)
+		if len(results) != len(step.want) {
+			t.Fatalf("tick %s: probed %v, want %v", step.by, results, step.want)
+		}
+		for _, name := range step.want {
+			if _, ok := results[name]; !ok {
+				t.Fatalf("tick %s: %s not probed, got %v", step.by, name, results)
+			}
+		}
+	}
+	if !d.Healthy() {
+		t.Fatal("doctor unhealthy")
+	}
+}
+
+func TestTickBacksOff(t *testing.T) {
+	d := New()
+	defer d.Stop()
+	err := d.Investigate(&doctor.Check{
+		Name:          "db",
+		Interval:      10 * time.Second,
+		Timeout:       time.Second,
+		BackoffFactor: 2,
+		MaxBackoff:    30 * time.Second,
+		Handler: func(context.Context) error {
+			return errors.New("down")
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 10s after the first failure, then doubled up to the maximum
+	if at, want := ticks(d, "db", 100*time.Second), []int{0, 10, 30, 60, 90}; !equal(at, want) {
+		t.Fatalf("probed at %v, want %v", at, want)
+	}
+}
+
+func TestTickJitters(t *testing.T) {
+	d := New()
+	defer d.Stop()
+	err := d.Investigate(&doctor.Check{
+		Name:           "db",
+		Interval:       10 * time.Second,
+		IntervalJitter: 3 * time.Second,
+		Timeout:        time.Second,
+		Rand:           rand.New(rand.NewSource(1)),
+		Handler: func(context.Context) error {
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first probe is offset within the jitter, the next ones vary around the interval
+	d.Tick(context.Background(), 0)
+	at := ticks(d, "db", 200*time.Second)
+	if len(at) < 15 {
+		t.Fatalf("probed at %v", at)
+	}
+	gaps := map[int]bool{}
+	for i := 1; i < len(at); i++ {
+		gap := at[i] - at[i-1]
+		if gap < 7 || gap > 13 {
+			t.Fatalf("probed %ds apart, outside the jitter: %v", gap, at)
+		}
+		gaps[gap] = true
+	}
+	if len(gaps) < 2 {
+		t.Fatalf("no jitter, probed at %v", at)
+	}
+}
+
+func TestTickPassesRetryDelays(t *testing.T) {
+	d := New()
+	defer d.Stop()
+	calls := 0
+	err := d.Investigate(&doctor.Check{
+		Name:       "db",
+		Interval:   time.Minute,
+		Timeout:    10 * time.Second,
+		Retries:    2,
+		RetryDelay: time.Second,
+		Handler: func(context.Context) error {
+			if calls++; calls < 3 {
+				return errors.New("down")
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	begin := d.Clock.Now()
+	done := make(chan map[string]error)
+	go func() {
+		done <- d.Tick(context.Background(), 0)
+	}()
+	select {
+	case results := <-done:
+		if err, ok := results["db"]; !ok || err != nil {
+			t.Fatalf("results %v, want a success after the retries", results)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("tick hangs on the retry delay")
+	}
+	if took := d.Clock.Now().Sub(begin); took != 2*time.Second {
+		t.Fatalf("retries took %s on the clock, want 2s", took)
+	}
+}
@@ -0,0 +1,95 @@
+package doctor_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/decoomanj/doctor"
+	"github.com/decoomanj/doctor/doctortest"
+)
+
+// drainable returns a listener on the fake clock of the doctor, with a drain delay of 10s
+func drainable(t *testing.T, d *doctortest.Doctor, opts ...doctor.ListenerOption) doctor.Listener {
+	t.Helper()
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln := doctor.NewListener(tcp, d.Doctor, append([]doctor.ListenerOption{doctor.WithDrainDelay(10 * time.Second)}, opts...)...)
+	t.Cleanup(func() { ln.Close() })
+	return ln
+}
+
+// open dials the listener and tells whether the accepted connection is open, or the error of
+// Accept
+func open(t *testing.T, ln doctor.Listener) (bool, error) {
+	t.Helper()
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	c, err := ln.Accept()
+	if err != nil {
+		return false, err
+	}
+	defer c.Close()
+	_, err = c.Write([]byte("ok"))
+	return err == nil, nil
+}
+
+func TestUnhealthyClosesAfterDelayWithoutDraining(t *testing.T) {
+	d := doctortest.New()
+	defer d.Stop()
+	ln := drainable(t, d, doctor.WithStopAccepting())
+
+	d.SetUnhealthy("maintenance")
+	if ok, err := open(t, ln); !ok || err != nil {
+		t.Fatalf("first unhealthy accept: open %t, error %v", ok, err)
+	}
+	d.Clock.Advance(10 * time.Second)
+	if ok, err := open(t, ln); ok || err != nil {
+		t.Fatalf("unhealthy beyond the delay: open %t, error %v, want closed without ErrDrained", ok, err)
+	}
+
+	// the window restarts after a recovery
+	d.ClearOverride()
+	if ok, err := open(t, ln); !ok || err != nil {
+		t.Fatalf("recovered: open %t, error %v", ok, err)
+	}
+	d.SetUnhealthy("maintenance")
+	for _, want := range []bool{true, true, false} {
+		if ok, err := open(t, ln); ok != want || err != nil {
+			t.Fatalf("unhealthy again at %s: open %t, error %v, want open %t", d.Clock.Now().Format(time.TimeOnly), ok, err, want)
+		}
+		d.Clock.Advance(5 * time.Second)
+	}
+}
+
+func TestDrainStopsAcceptingAfterDelay(t *testing.T) {
+	d := doctortest.New()
+	defer d.Stop()
+	ln := drainable(t, d, doctor.WithStopAccepting())
+	if ok, err := open(t, ln); !ok || err != nil {
+		t.Fatalf("before the drain: open %t, error %v", ok, err)
+	}
+
+	ln.Drain()
+	d.Clock.Advance(9 * time.Second)
+	if ok, err := open(t, ln); !ok || err != nil {
+		t.Fatalf("within the drain delay: open %t, error %v", ok, err)
+	}
+	d.Clock.Advance(time.Second)
+	if _, err := open(t, ln); !errors.Is(err, doctor.ErrDrained) {
+		t.Fatalf("after the drain delay: error %v, want ErrDrained", err)
+	}
+
+	// a recovery does not end an explicit drain
+	d.SetUnhealthy("blip")
+	d.ClearOverride()
+	if _, err := open(t, ln); !errors.Is(err, doctor.ErrDrained) {
+		t.Fatalf("after a recovery: error %v, want ErrDrained", err)
+	}
+}
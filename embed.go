@@ -0,0 +1,52 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Embed registers the aggregate health of a child doctor as a single check of the parent, so
+// subsystems can have their own health and still roll up into the parent. The check follows
+// the changes of the child instead of probing it. The child keeps its own endpoints.
+func (health *Doctor) Embed(name string, child *Doctor) error {
+	check := &Check{
+		Name: name,
+		Handler: func(context.Context) error {
+			if child.Healthy() {
+				return nil
+			}
+			failing := make([]string, 0)
+			for check := range child.checks.failing() {
+				failing = append(failing, check)
+			}
+			sort.Strings(failing)
+			return fmt.Errorf("failing: %s", strings.Join(failing, ", "))
+		},
+	}
+	return health.register(health.ctx, check, child)
+}
+
+// embed evaluates the child doctor whenever its state may have changed, until the check is
+// done. This covers removed and disabled checks, a reset and an override of the child too.
+func (hc *healthCheckStatus) embed(health *Doctor) {
+	changes := make(chan struct{}, 1)
+	unwatch := hc.follow.OnChange(func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	})
+	defer unwatch()
+
+	for {
+		_ = hc.probe(hc.ctx, health, hc.next())
+
+		select {
+		case <-hc.ctx.Done():
+			return
+		case <-changes:
+		}
+	}
+}
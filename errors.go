@@ -0,0 +1,148 @@
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrPushCheck is returned when a push check is probed, it reports with Heartbeat instead
+var ErrPushCheck = errors.New("push check cannot be probed")
+
+// ErrStopped is returned when a check is registered after Stop
+var ErrStopped = errors.New("doctor stopped")
+
+// ErrDrained is returned by Accept when the listener stopped accepting, see WithStopAccepting
+var ErrDrained = errors.New("listener drained")
+
+// CheckError lets a handler or an aspect attach details to its result, e.g. the number of open
+// connections. The details are shown in the verbose output. A CheckError without Err reports
+// details of a successful probe.
+type CheckError struct {
+	Err    error
+	Detail map[string]any
+}
+
+// Error implements error
+func (e *CheckError) Error() string {
+	if e.Err == nil {
+		return ""
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *CheckError) Unwrap() error {
+	return e.Err
+}
+
+// HealthDetailer is recognized in the chain of a failure to report it machine-readable, e.g. an
+// error code and a hint how to remedy it. Both are shown in the status and the verbose output,
+// next to the message of Error.
+type HealthDetailer interface {
+	error
+
+	// Code identifies the failure, e.g. "DB_UNREACHABLE"
+	Code() string
+
+	// Hint tells how to remedy the failure
+	Hint() string
+}
+
+// Warning lets a handler or an aspect report a problem which does not make the check unhealthy,
+// e.g. a disk filling up. The warning is shown in the verbose output. An aspect may downgrade
+// the error of the handler to a warning.
+type Warning struct {
+	Err error
+}
+
+// Error implements error
+func (w *Warning) Error() string {
+	if w.Err == nil {
+		return "warning"
+	}
+	return w.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (w *Warning) Unwrap() error {
+	return w.Err
+}
+
+// Failed tells whether the result of a handler fails the check. A Warning and a CheckError which
+// only carries details do not, e.g. for instrumentation which wraps a handler.
+func Failed(err error) bool {
+	return failure(err) != nil
+}
+
+// failure returns the error of a probe result, nil for a CheckError which only carries details
+// and for a Warning
+func failure(err error) error {
+	var warning *Warning
+	if errors.As(err, &warning) {
+		return nil
+	}
+	var checkErr *CheckError
+	if errors.As(err, &checkErr) && checkErr.Err == nil {
+		return nil
+	}
+	return err
+}
+
+// The sources of a failure, see CheckStatus
+const (
+	SourceHandler = "handler"
+	SourceAspect  = "aspect"
+)
+
+// aspectError marks a failure which the aspect raised rather than the handler
+type aspectError struct {
+	err error
+}
+
+// Error implements error
+func (e *aspectError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the error of the aspect
+func (e *aspectError) Unwrap() error {
+	return e.err
+}
+
+// timeoutError marks a probe which did not finish within the timeout of the check
+type timeoutError struct {
+	timeout time.Duration
+	err     error
+}
+
+// Error implements error
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s", e.timeout)
+}
+
+// Unwrap returns the error of the probe
+func (e *timeoutError) Unwrap() error {
+	return e.err
+}
+
+// NotReadyError is returned by WaitReady when the checks do not become ready in time
+type NotReadyError struct {
+
+	// The names of the checks which never succeeded, sorted
+	Checks []string
+
+	// The error of the context
+	Err error
+}
+
+// Error implements error
+func (e *NotReadyError) Error() string {
+	return fmt.Sprintf("health-checks not ready: %s: %v", strings.Join(e.Checks, ", "), e.Err)
+}
+
+// Unwrap returns the error of the context
+func (e *NotReadyError) Unwrap() error {
+	return e.Err
+}
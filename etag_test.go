@@ -0,0 +1,88 @@
+package doctor_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/decoomanj/doctor"
+	"github.com/decoomanj/doctor/doctortest"
+)
+
+// get serves a request of the method on the target with the given headers
+func get(d *doctortest.Doctor, method, target string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Add(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	d.Handler(w, r)
+	return w
+}
+
+func TestETagAnswersUnchangedStateWithNotModified(t *testing.T) {
+	d := doctortest.New()
+	defer d.Stop()
+	down := false
+	err := d.Investigate(&doctor.Check{
+		Name:     "db",
+		Interval: 10 * time.Second,
+		Timeout:  time.Second,
+		Handler: func(context.Context) error {
+			if down {
+				return errors.New("down")
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Tick(context.Background(), 0)
+
+	w := get(d, http.MethodGet, doctor.PathHealth)
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" {
+		t.Fatalf("status %d with ETag %q", w.Code, etag)
+	}
+	w = get(d, http.MethodGet, doctor.PathHealth, "If-None-Match", etag)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("unchanged state: status %d, body %q, Cache-Control %q", w.Code, w.Body, w.Header().Get("Cache-Control"))
+	}
+	if w = get(d, http.MethodGet, doctor.PathHealth+"?verbose=1", "If-None-Match", etag); w.Code != http.StatusOK {
+		t.Fatalf("verbose request: status %d, want the full page", w.Code)
+	}
+
+	// a failing service is always served in full, whatever the poller saw before
+	down = true
+	d.Tick(context.Background(), 10*time.Second)
+	w = get(d, http.MethodGet, doctor.PathHealth, "If-None-Match", etag)
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("ETag") != "" {
+		t.Fatalf("failing: status %d with ETag %q", w.Code, w.Header().Get("ETag"))
+	}
+
+	down = false
+	d.Tick(context.Background(), 10*time.Second)
+	if w = get(d, http.MethodGet, doctor.PathHealth, "If-None-Match", etag); w.Code != http.StatusNotModified {
+		t.Fatalf("recovered to the same state: status %d", w.Code)
+	}
+	if w = get(d, http.MethodGet, doctor.PathHealth, "If-None-Match", `"stale"`); w.Code != http.StatusOK {
+		t.Fatalf("other ETag: status %d", w.Code)
+	}
+}
+
+func TestHeadOmitsBody(t *testing.T) {
+	d := doctortest.New()
+	defer d.Stop()
+	w := get(d, http.MethodHead, doctor.PathHealth)
+	if w.Code != http.StatusOK || w.Body.Len() != 0 || w.Header().Get("Content-Type") == "" {
+		t.Fatalf("status %d, body %q, Content-Type %q", w.Code, w.Body, w.Header().Get("Content-Type"))
+	}
+	d.SetUnhealthy("maintenance")
+	if w = get(d, http.MethodHead, doctor.PathHealth); w.Code != http.StatusServiceUnavailable || w.Body.Len() != 0 {
+		t.Fatalf("unhealthy: status %d, body %q", w.Code, w.Body)
+	}
+}
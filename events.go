@@ -0,0 +1,72 @@
+package doctor
+
+import (
+	"sync"
+	"time"
+)
+
+// the capacity of the event channel
+const eventBuffer = 64
+
+// Event is a change of the health of a check, or of the whole service when Check is empty
+type Event struct {
+
+	// The name of the check, empty for the service
+	Check string
+
+	// Whether the check or the service became healthy
+	Healthy bool
+
+	// When the change happened
+	Time time.Time
+}
+
+// events is the event channel of a doctor, guarded against sending after close
+type events struct {
+	sync.RWMutex
+	ch     chan Event
+	closed bool
+}
+
+// Events returns a channel with the changes of the checks and of the whole service. The channel
+// buffers 64 events; when the consumer falls behind, new events are dropped rather than blocking
+// the probes. The channel is closed by Stop.
+func (health *Doctor) Events() <-chan Event {
+	return health.events.ch
+}
+
+// aggregate emits an event when the health of the whole service changed since the last call,
+// updates the status file and notifies the listeners of OnChange
+func (health *Doctor) aggregate() {
+	if health.file != nil {
+		health.persist()
+	}
+	healthy := health.Healthy()
+	if health.up.Swap(healthy) != healthy {
+		health.events.emit(Event{Healthy: healthy, Time: health.clock.Now()})
+	}
+	health.changes.notify()
+}
+
+// emit an event, dropping it when the buffer is full
+func (e *events) emit(event Event) {
+	e.RLock()
+	defer e.RUnlock()
+	if e.closed {
+		return
+	}
+	select {
+	case e.ch <- event:
+	default:
+	}
+}
+
+// close the channel, once
+func (e *events) close() {
+	e.Lock()
+	defer e.Unlock()
+	if !e.closed {
+		e.closed = true
+		close(e.ch)
+	}
+}
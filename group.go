@@ -0,0 +1,69 @@
+package doctor
+
+import (
+	"fmt"
+	"sync"
+)
+
+// healthGroup holds a set of checks which is healthy as long as a quorum of them is healthy. The
+// group takes a position in the status of the doctor, its members set their bits in the status
+// of the group instead.
+type healthGroup struct {
+	sync.Mutex
+	min     int
+	members int
+	pos     uint
+	bits    *healthStatus
+}
+
+// Group defines a group of checks which is healthy as long as at least minHealthy of its members
+// are healthy, e.g. 2 out of 3 replicas. Checks join the group with Check.Group; the group must be
+// defined before its members are registered. The members are still reported individually.
+func (health *Doctor) Group(name string, minHealthy int) error {
+	health.checks.Lock()
+	defer health.checks.Unlock()
+	if _, ok := health.checks.groups[name]; ok {
+		return fmt.Errorf("health-check group %q already defined", name)
+	}
+	group := &healthGroup{
+		min:  minHealthy,
+		pos:  health.checks.alloc(),
+		bits: &healthStatus{},
+	}
+	health.checks.groups[name] = group
+	group.refresh(health.status)
+	return nil
+}
+
+// join adds (or removes, with a negative delta) members to the group
+func (g *healthGroup) join(delta int, status *healthStatus) {
+	g.Lock()
+	g.members += delta
+	g.Unlock()
+	g.refresh(status)
+}
+
+// refresh the bit of the group from the state of its members
+func (g *healthGroup) refresh(status *healthStatus) {
+	g.Lock()
+	defer g.Unlock()
+	status.update(g.pos, g.members-g.bits.count() >= g.min)
+}
+
+// regroup refreshes the bit of the group of the check. Once the check is removed, reset or
+// stopped it is left alone: the group may be gone and its position reused by another check.
+func (hc *healthCheckStatus) regroup(health *Doctor) {
+	if hc.group == nil {
+		return
+	}
+	hc.RLock()
+	defer hc.RUnlock()
+	if hc.ctx.Err() == nil {
+		hc.group.refresh(health.status)
+	}
+}
+
+// count returns the number of bits set
+func (c *healthStatus) count() int {
+	return int(c.set.Load())
+}
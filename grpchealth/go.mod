@@ -0,0 +1,16 @@
+module github.com/decoomanj/doctor/grpchealth
+
+go 1.21.1
+
+require (
+	github.com/decoomanj/doctor v0.0.0-20261014183040-f426c7cb9bd6
+	google.golang.org/grpc v1.64.1
+)
+
+require (
+	golang.org/x/net v0.26.0 // indirect
+	golang.org/x/sys v0.22.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237 // indirect
+	google.golang.org/protobuf v1.34.2 // indirect
+)
@@ -0,0 +1,111 @@
+package grpchealth
+
+import (
+	"context"
+
+	"github.com/decoomanj/doctor"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+type (
+	// Server adapts a doctor to the grpc health checking protocol. The empty service name reports
+	// the aggregate health of the doctor, any other name the health of the checks mapped to it.
+	Server struct {
+		healthpb.UnimplementedHealthServer
+		health  *doctor.Doctor
+		service func(check string) string
+	}
+
+	// Option configures the server
+	Option func(*Server)
+)
+
+// WithServiceMapping maps check names to service names. By default every check is exposed as a
+// service with the same name. When multiple checks map to the same service, the service is
+// serving when all of them are healthy.
+func WithServiceMapping(mapping func(check string) string) Option {
+	return func(s *Server) {
+		s.service = mapping
+	}
+}
+
+// NewServer creates a grpc health server for the given doctor
+func NewServer(health *doctor.Doctor, opts ...Option) *Server {
+	s := &Server{
+		health: health,
+		service: func(check string) string {
+			return check
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Check implements healthpb.HealthServer
+func (s *Server) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	serving, ok := s.status(req.GetService())
+	if !ok {
+		return nil, status.Error(codes.NotFound, "unknown service")
+	}
+	return &healthpb.HealthCheckResponse{Status: serving}, nil
+}
+
+// Watch implements healthpb.HealthServer. An update is sent whenever the serving status of the
+// service changes.
+func (s *Server) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	changes := make(chan struct{}, 1)
+	unwatch := s.health.OnChange(func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	})
+	defer unwatch()
+
+	last := healthpb.HealthCheckResponse_ServingStatus(-1)
+	for {
+		serving, ok := s.status(req.GetService())
+		if !ok {
+			serving = healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		}
+		if serving != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: serving}); err != nil {
+				return err
+			}
+			last = serving
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return status.Error(codes.Canceled, "stream has ended")
+		case <-changes:
+		}
+	}
+}
+
+// status returns the serving status of a service and whether the service is known
+func (s *Server) status(service string) (healthpb.HealthCheckResponse_ServingStatus, bool) {
+	if service == "" {
+		return serving(s.health.Healthy()), true
+	}
+	found, healthy := false, true
+	for _, check := range s.health.Status() {
+		if s.service(check.Name) == service {
+			found = true
+			healthy = healthy && check.Healthy
+		}
+	}
+	return serving(healthy), found
+}
+
+// serving maps a health state to a serving status
+func serving(healthy bool) healthpb.HealthCheckResponse_ServingStatus {
+	if healthy {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
@@ -0,0 +1,407 @@
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// checkView is the verbose JSON representation of a check
+type checkView struct {
+	Name        string         `json:"name"`
+	Status      string         `json:"status"`
+	Message     string         `json:"message,omitempty"`
+	Warning     string         `json:"warning,omitempty"`
+	Source      string         `json:"source,omitempty"`
+	Interrupted bool           `json:"interrupted,omitempty"`
+	TimedOut    bool           `json:"timedOut,omitempty"`
+	Breaker     string         `json:"breaker,omitempty"`
+	Detail      map[string]any `json:"detail,omitempty"`
+	Code        string         `json:"code,omitempty"`
+	Hint        string         `json:"hint,omitempty"`
+	LastChecked *time.Time     `json:"lastChecked,omitempty"`
+	LastSuccess *time.Time     `json:"lastSuccess,omitempty"`
+	LastFailure *time.Time     `json:"lastFailure,omitempty"`
+	Duration    string         `json:"duration"`
+	Latency     *latencyView   `json:"latency,omitempty"`
+}
+
+// failureView is the JSON representation of a failing check in the ordered list of failures
+type failureView struct {
+	Name     string `json:"name"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// latencyView is the JSON representation of the latency percentiles of a check
+type latencyView struct {
+	P50 string `json:"p50"`
+	P95 string `json:"p95"`
+	P99 string `json:"p99"`
+}
+
+// ServeHTTP implements http.Handler with Handler, so the doctor can be mounted directly
+func (health *Doctor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	health.Handler(w, r)
+}
+
+// HandlerFunc returns Handler as http.HandlerFunc
+func (health *Doctor) HandlerFunc() http.HandlerFunc {
+	return health.Handler
+}
+
+// ChangedHeader is the response header of Handler which tells whether the state changed since
+// the previous response, see WithChangeHeader
+const ChangedHeader = "X-Health-Changed"
+
+// served remembers the status bits last served by Handler, see WithChangeHeader
+type served struct {
+	sync.Mutex
+	last string
+}
+
+// Handler renders the health status page of all the checks. Add the query parameter verbose=1
+// to list every check with its state, and tag to report over the checks with one of the given
+// tags only. While the service is up, the terse response carries an
+// ETag derived from the status bits, so pollers can use If-None-Match to get a 304. The verbose
+// page is subject to WithRateLimit, the terse one is not, also with a renderer.
+func (health *Doctor) Handler(w http.ResponseWriter, r *http.Request) {
+	if verbose(r) && health.limit(w) {
+		return
+	}
+	health.page(w, r)
+}
+
+// page renders the health status page of all the checks, see Handler
+func (health *Doctor) page(w http.ResponseWriter, r *http.Request) {
+	all := func(*healthCheckStatus) bool {
+		return true
+	}
+	if health.served != nil {
+		w.Header().Set(ChangedHeader, strconv.FormatBool(health.served.changed(health.etag())))
+	}
+	if health.renderer != nil {
+		health.custom(w, r)
+		return
+	}
+	if tags := r.URL.Query()["tag"]; len(tags) > 0 {
+		health.serve(w, r, tagged(tags), true)
+		return
+	}
+
+	// do not hold the status lock while collecting the failing checks, probes take the
+	// check lock before the status lock
+	switch {
+	case !health.Healthy() && health.startup:
+		state, errors := health.checks.evaluate(all, true)
+		if state != statusStarting || health.override.Load() != nil {
+			// the policy may be stricter than the checks, or the service is forced unhealthy
+			state = statusDown
+		}
+		health.render(w, r, state, errors, all)
+	case !health.Healthy():
+		health.render(w, r, statusDown, health.checks.failing(), all)
+	case health.Degraded():
+		health.render(w, r, statusDegraded, health.checks.failing(), all)
+	default:
+		etag := health.etag()
+		w.Header().Set("ETag", etag)
+		if !verbose(r) && r.Header.Get("If-None-Match") == etag {
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		health.render(w, r, statusUp, nil, all)
+	}
+}
+
+// ProbeHandler probes the checks before rendering the health status page like Handler. Select
+// the checks with one or more check query parameters, otherwise all checks are probed. A probe
+// in flight is joined rather than repeated. A check waits at most twice its timeout; when it
+// does not finish in time, or the client goes away, it keeps its last known state. The probes
+// carry the X-Request-Id header of the request as probe ID, see ProbeID.
+func (health *Doctor) ProbeHandler(w http.ResponseWriter, r *http.Request) {
+	if health.limit(w) {
+		return
+	}
+	var wg sync.WaitGroup
+	for _, check := range health.checks.selection(r.URL.Query()["check"]) {
+		wg.Add(1)
+		go func(check *healthCheckStatus) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(probeContext(r), 2*check.timeout())
+			defer cancel()
+			_ = check.run(ctx, health)
+		}(check)
+	}
+	wg.Wait()
+
+	if r.Context().Err() != nil {
+		// nobody is listening anymore
+		return
+	}
+	health.page(w, r)
+}
+
+// probeContext returns the context of the request, with the request ID as probe ID if there is one
+func probeContext(r *http.Request) context.Context {
+	if id := r.Header.Get(RequestIDHeader); id != "" {
+		return WithProbeID(r.Context(), id)
+	}
+	return r.Context()
+}
+
+// LivenessHandler renders the health status page of the liveness checks
+func (health *Doctor) LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	health.serve(w, r, func(hc *healthCheckStatus) bool {
+		return hc.Kind == Liveness
+	}, false)
+}
+
+// ReadinessHandler renders the health status page of the readiness checks. With a health
+// policy, the policy decides like it does for Healthy, so readiness agrees with Handler and
+// Listener; the policy sees all the checks then, as a stateful policy like PercentUnhealthy
+// must always see the same set. LivenessHandler and the tag parameter of Handler bypass it.
+func (health *Doctor) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	readiness := func(hc *healthCheckStatus) bool {
+		return hc.Kind == Readiness
+	}
+	if health.policy == nil {
+		health.serve(w, r, readiness, true)
+		return
+	}
+
+	state, errors := health.checks.evaluate(readiness, health.startup)
+	switch healthy := health.Healthy(); {
+	case !healthy && (state != statusStarting || health.override.Load() != nil):
+		state = statusDown
+	case healthy && state != statusDegraded:
+		state = statusUp
+	}
+	health.render(w, r, state, errors, readiness)
+}
+
+// StartupHandler renders the health status page for a startup probe. Until every check has run
+// at least once, it reports the service as starting with the unhealthy status code and lists
+// the checks which did not run yet. From then on it is the ReadinessHandler, also when checks
+// are registered later.
+func (health *Doctor) StartupHandler(w http.ResponseWriter, r *http.Request) {
+	if unprobed := health.booting(); len(unprobed) > 0 {
+		errors := make(map[string]string, len(unprobed))
+		for _, name := range unprobed {
+			errors[name] = "not probed yet"
+		}
+		health.render(w, r, statusStarting, errors, func(hc *healthCheckStatus) bool {
+			return true
+		})
+		return
+	}
+	health.ReadinessHandler(w, r)
+}
+
+// serve the health status page of the matching checks, down while forced unhealthy when the
+// override applies
+func (health *Doctor) serve(w http.ResponseWriter, r *http.Request, match func(*healthCheckStatus) bool, overridable bool) {
+	state, errors := health.checks.evaluate(match, health.startup)
+	if overridable && health.override.Load() != nil {
+		state = statusDown
+	}
+	health.render(w, r, state, errors, match)
+}
+
+// render the health status page. Only a down or starting service is reported with the unhealthy
+// status code.
+func (health *Doctor) render(w http.ResponseWriter, r *http.Request, state string, errors map[string]string, match func(*healthCheckStatus) bool) {
+	var status = struct {
+		Status   string            `json:"status"`
+		Override string            `json:"override,omitempty"`
+		Errors   map[string]string `json:"errors,omitempty"`
+		Failures []failureView     `json:"failures,omitempty"`
+		Checks   []checkView       `json:"checks,omitempty"`
+		Metadata map[string]string `json:"metadata,omitempty"`
+		Started  *time.Time        `json:"started,omitempty"`
+		Uptime   string            `json:"uptime,omitempty"`
+	}{
+		Status: state,
+		Errors: errors,
+	}
+	if override := health.override.Load(); override != nil && state == statusDown {
+		status.Override = *override
+	}
+	if verbose(r) {
+		status.Metadata = health.metadata
+		status.Started = &health.started
+		status.Uptime = health.clock.Now().Sub(health.started).Truncate(time.Second).String()
+		checks := health.checks.statuses(match)
+		for _, check := range checks {
+			status.Checks = append(status.Checks, view(check))
+		}
+		status.Failures = failures(checks)
+	}
+
+	up := state == statusUp || state == statusDegraded
+	statusCode := health.healthyStatus
+	if !up {
+		statusCode = health.unhealthyStatus
+	}
+
+	write(w, r, statusCode, up, status)
+}
+
+// custom renders the health status page with the renderer of the doctor
+func (health *Doctor) custom(w http.ResponseWriter, r *http.Request) {
+	snapshot := health.Snapshot()
+	statusCode := health.healthyStatus
+	if !snapshot.Healthy {
+		statusCode = health.unhealthyStatus
+	}
+
+	write(w, r, statusCode, snapshot.Healthy, health.renderer(snapshot))
+}
+
+// write the health status page, as JSON or as plain text when the client prefers it. The plain
+// text is OK or UNAVAILABLE, following the health.
+func write(w http.ResponseWriter, r *http.Request, statusCode int, up bool, body any) {
+	// never let intermediaries cache a stale state, and skip the body for HEAD probes
+	w.Header().Set("Cache-Control", "no-store")
+	if plain(r) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(statusCode)
+		if r.Method != http.MethodHead {
+			text := "OK"
+			if !up {
+				text = "UNAVAILABLE"
+			}
+			_, _ = io.WriteString(w, text+"\n")
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	if r.Method != http.MethodHead {
+		_ = json.NewEncoder(w).Encode(body)
+	}
+}
+
+// plain tells whether the client accepts plain text before JSON. Quality values are ignored,
+// the first known media type wins; JSON is the default.
+func plain(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, media := range strings.Split(accept, ",") {
+			media, _, _ = strings.Cut(media, ";")
+			switch strings.TrimSpace(media) {
+			case "text/plain", "text/*":
+				return true
+			case "application/json", "application/*", "*/*":
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// changed tells whether the status bits differ from the ones served last, and remembers them
+func (s *served) changed(bits string) bool {
+	s.Lock()
+	defer s.Unlock()
+	changed := s.last != "" && s.last != bits
+	s.last = bits
+	return changed
+}
+
+// tagged matches the checks which carry one of the tags
+func tagged(tags []string) func(*healthCheckStatus) bool {
+	return func(hc *healthCheckStatus) bool {
+		for _, tag := range hc.Tags {
+			for _, want := range tags {
+				if tag == want {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// verbose tells whether the request asks for the verbose output
+func verbose(r *http.Request) bool {
+	verbose, _ := strconv.ParseBool(r.URL.Query().Get("verbose"))
+	return verbose
+}
+
+// etag derives an entity tag from the status bits
+func (health *Doctor) etag() string {
+	return `"` + health.status.hex() + "-" + health.optional.hex() + `"`
+}
+
+// failures lists the failing checks, the critical ones first and then by name, so the most
+// important failure comes first and the order is stable
+func failures(checks []CheckStatus) []failureView {
+	var failing []CheckStatus
+	for _, check := range checks {
+		if !check.Healthy && !check.Starting && !check.Unknown && !check.Disabled && !check.Skipped {
+			failing = append(failing, check)
+		}
+	}
+	sort.SliceStable(failing, func(i, j int) bool {
+		return failing[i].Severity < failing[j].Severity
+	})
+	views := make([]failureView, 0, len(failing))
+	for _, check := range failing {
+		views = append(views, failureView{Name: check.Name, Severity: check.Severity.String(), Message: check.Message})
+	}
+	return views
+}
+
+// view converts the state of a check to its JSON representation
+func view(check CheckStatus) checkView {
+	v := checkView{
+		Name:        check.Name,
+		Status:      statusUp,
+		Message:     check.Message,
+		Warning:     check.Warning,
+		Source:      check.Source,
+		Interrupted: check.Interrupted,
+		TimedOut:    check.TimedOut,
+		Breaker:     check.Breaker,
+		Detail:      check.Detail,
+		Code:        check.Code,
+		Hint:        check.Hint,
+		Duration:    check.Duration.String(),
+	}
+	switch {
+	case check.Disabled:
+		v.Status = "disabled"
+	case check.Skipped:
+		v.Status = "skipped"
+	case check.Starting:
+		v.Status = statusStarting
+	case check.Unknown:
+		v.Status = "unknown"
+	case !check.Healthy:
+		v.Status = statusDown
+	}
+	if !check.LastRun.IsZero() {
+		v.LastChecked = &check.LastRun
+	}
+	if !check.LastSuccess.IsZero() {
+		v.LastSuccess = &check.LastSuccess
+	}
+	if !check.LastFailure.IsZero() {
+		v.LastFailure = &check.LastFailure
+	}
+	if check.Latency != nil {
+		v.Latency = &latencyView{
+			P50: check.Latency.P50.String(),
+			P95: check.Latency.P95.String(),
+			P99: check.Latency.P99.String(),
+		}
+	}
+	return v
+}
@@ -0,0 +1,98 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHandlerDoesNotWaitForSlowProbe(t *testing.T) {
+	health := NewDoctor()
+	started, release := make(chan struct{}), make(chan struct{})
+	t.Cleanup(func() {
+		close(release)
+		health.Stop()
+	})
+	var once sync.Once
+	err := health.InvestigateAll(context.Background(), &Check{
+		Name:     "slow",
+		Interval: 10 * time.Second,
+		Timeout:  5 * time.Second,
+		Handler: func(ctx context.Context) error {
+			once.Do(func() { close(started) })
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+			return nil
+		},
+	}, &Check{
+		Name:     "down",
+		Interval: 10 * time.Second,
+		Handler: func(context.Context) error {
+			return errors.New("down")
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	health.RunCheck(context.Background(), "down")
+	<-started
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				w := httptest.NewRecorder()
+				health.Handler(w, httptest.NewRequest(http.MethodGet, PathHealth, nil))
+				if w.Code != http.StatusServiceUnavailable {
+					t.Errorf("status %d, want %d", w.Code, http.StatusServiceUnavailable)
+				}
+			}()
+		}
+		wg.Wait()
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handler calls blocked behind the slow probe")
+	}
+}
+
+func TestRateLimitSparesTerseRequests(t *testing.T) {
+	health := NewDoctor(WithRateLimit(0, 1), WithRenderer(func(s Snapshot) any {
+		return s.Healthy
+	}))
+	t.Cleanup(health.Stop)
+	serve := func(handler http.HandlerFunc, target string) int {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w.Code
+	}
+
+	for i := 0; i < 5; i++ {
+		if code := serve(health.Handler, PathHealth); code != http.StatusOK {
+			t.Fatalf("terse request %d: status %d", i, code)
+		}
+	}
+	if code := serve(health.Handler, PathHealth+"?verbose=1"); code != http.StatusOK {
+		t.Fatalf("first verbose request: status %d", code)
+	}
+	if code := serve(health.Handler, PathHealth+"?verbose=1"); code != http.StatusTooManyRequests {
+		t.Fatalf("verbose request beyond the limit: status %d", code)
+	}
+	if code := serve(health.ProbeHandler, PathProbe); code != http.StatusTooManyRequests {
+		t.Fatalf("probe request beyond the limit: status %d", code)
+	}
+	if code := serve(health.Handler, PathHealth); code != http.StatusOK {
+		t.Fatalf("terse request beyond the limit: status %d", code)
+	}
+}
@@ -2,10 +2,16 @@ package doctor
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
 	"net/http"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -16,161 +22,1475 @@ type (
 		// The unique name of the check.
 		Name string
 
-		// The actual health-check function
+		// The actual health-check function. Leave it nil for a push check, which reports with
+		// Heartbeat instead of being probed, or for a check driven by Results.
 		Handler func(context.Context) error
 
-		// The interval to query the healthfunc
+		// The results of a check which reports by itself, leave Handler nil. Every value is applied
+		// as the result of a probe, nil being healthy.
+		Results <-chan error
+
+		// Remove a check driven by Results when its channel is closed. Otherwise the check stays
+		// registered as failing.
+		RemoveOnClose bool
+
+		// The time a push check stays healthy after its last heartbeat
+		TTL time.Duration
+
+		// The interval to query the healthfunc, DefaultInterval when zero
 		Interval time.Duration
 
-		// The timeout for the healthfunc duration
+		// Probe the check only once and keep its result for the lifetime of the doctor, e.g. for a
+		// check of the kernel version. A probe which is skipped or held back by the breaker is
+		// retried on the interval until a result is recorded. RunCheck still probes it again.
+		Once bool
+
+		// The timeout for the healthfunc duration, DefaultTimeout when zero. It must be shorter
+		// than the interval.
 		Timeout time.Duration
 
+		// Detach the check from the deadline and cancellation of the context it is registered with,
+		// so only its own timeout bounds its probes. A detached check still stops on Remove, Stop and
+		// when the root context of the doctor is done, but outlives the context it is registered with.
+		Detached bool
+
+		// The maximum random deviation of the interval, to spread the probes of checks with the same
+		// interval. The first probe is offset randomly within the jitter as well. It must be
+		// shorter than the interval.
+		IntervalJitter time.Duration
+
+		// The factor to multiply the interval with after every consecutive failure while the check
+		// is unhealthy. The interval snaps back once the check recovers. No backoff when 1 or less.
+		BackoffFactor float64
+
+		// The maximum interval while backing off, unbounded when zero
+		MaxBackoff time.Duration
+
+		// The random source for the jitter, seed it for deterministic intervals. A source seeded by
+		// the time is used when nil. It must not be shared between checks.
+		Rand *rand.Rand
+
+		// The delay before the first probe. The check is reported as starting (and not as failing)
+		// until the first probe has finished.
+		InitialDelay time.Duration
+
+		// Enrich the context of the probes, e.g. with values the handler needs. The timeout is
+		// applied on the returned context.
+		ContextFunc func(context.Context) context.Context
+
 		// Aspect to process the result
 		Aspect func(Check, error) error
+
+		// Aspect to process the result with the timing and the history of the check. It runs after
+		// Aspect, on its result.
+		ContextAspect func(AspectContext) error
+
+		// The kind of the check, readiness by default
+		Kind Kind
+
+		// The severity of the check, critical by default. A failing non-critical check degrades the
+		// service but keeps it up.
+		Severity Severity
+
+		// The names of the checks this check depends on. While one of them is unhealthy, this check
+		// is skipped instead of probed, and reported as skipped rather than failing.
+		DependsOn []string
+
+		// The tags of the check, e.g. the owning team. The status page reports over the checks with
+		// a tag when asked with the query parameter tag.
+		Tags []string
+
+		// The group of the check, see Doctor.Group. The quorum of the group determines the health
+		// instead of the check itself, its severity is ignored.
+		Group string
+
+		// The number of consecutive failures before a healthy check is marked unhealthy. Defaults to 1.
+		FailureThreshold int
+
+		// The number of consecutive successes before an unhealthy check is marked healthy. Defaults to 1.
+		SuccessThreshold int
+
+		// The time an unhealthy check must pass continuously before it is marked healthy again. A
+		// failure within the window restarts it. It applies on top of the success threshold.
+		Stabilization time.Duration
+
+		// The number of times a failing handler is retried within a single probe, before the
+		// failure is recorded. All attempts share the timeout of the probe.
+		Retries int
+
+		// The delay between the retries of a probe
+		RetryDelay time.Duration
+
+		// The number of consecutive failures which open the circuit breaker of the check: the
+		// scheduled probes stop for the cooldown, then a single trial probe decides whether
+		// probing resumes or the breaker opens again. No breaker when zero.
+		BreakerThreshold int
+
+		// The time the circuit breaker stays open
+		BreakerCooldown time.Duration
+
+		// Callback when the check becomes healthy or unhealthy. It is not called on every probe, only
+		// on transitions.
+		OnStateChange func(name string, healthy bool, err error)
+	}
+
+	// AspectContext holds the result of a probe together with the state of the check before it
+	AspectContext struct {
+		Check
+
+		// The error of the handler
+		Err error
+
+		// The duration of the probe
+		Duration time.Duration
+
+		// The number of consecutive failures before this probe
+		Failures int
+
+		// Whether the check was healthy before this probe
+		Healthy bool
 	}
 
 	// Doctor encapsulates all the health functionality
 	Doctor struct {
-		checks *healthChecks
-		status *healthStatus
+		ctx       context.Context
+		checks    *healthChecks
+		status    *healthStatus
+		optional  *healthStatus
+		watchers  *watchers
+		changes   *listeners
+		notifiers *notifiers
+		events    *events
+		clock     Clock
+		up        atomic.Bool
+		override  atomic.Pointer[string]
+		booted    atomic.Bool
+		wg        sync.WaitGroup
+
+		interval  time.Duration
+		timeout   time.Duration
+		maxChecks int
+		window    int
+		results   int
+		staleness float64
+		halt      context.Context
+		stop      context.CancelFunc
+		probes    chan struct{}
+		logger    *slog.Logger
+		policy    func([]CheckStatus) bool
+		renderer  func(Snapshot) any
+		startup   bool
+		sync      bool
+		manual    bool
+		metadata  map[string]string
+		started   time.Time
+		served    *served
+		limiter   *limiter
+		file      *statusFile
+
+		healthyStatus   int
+		unhealthyStatus int
 	}
 )
 
+// The defaults for checks which leave their interval or timeout zero, unless the doctor is
+// configured with other defaults
+const (
+	DefaultInterval = 30 * time.Second
+	DefaultTimeout  = 5 * time.Second
+)
+
+// Kind tells whether a check is about the readiness or the liveness of the service
+type Kind int
+
+const (
+	// Readiness checks fail when the service cannot handle traffic, e.g. a dependency is down
+	Readiness Kind = iota
+
+	// Liveness checks fail when the process itself is wedged and needs a restart
+	Liveness
+)
+
+// Severity tells whether a failing check takes the service down or only degrades it
+type Severity int
+
+const (
+	// Critical checks take the service down when they fail
+	Critical Severity = iota
+
+	// NonCritical checks only degrade the service when they fail
+	NonCritical
+)
+
+// String returns the name of the severity
+func (s Severity) String() string {
+	if s == NonCritical {
+		return "non-critical"
+	}
+	return "critical"
+}
+
+// the aggregated states of the service
+const (
+	statusUp       = "up"
+	statusDegraded = "degraded"
+	statusDown     = "down"
+	statusStarting = "starting"
+)
+
 // internal types
 type (
 	// healthStatus wraps the original check with internal fields to hold state
 	healthCheckStatus struct {
+
+		// The configuration of the check. It is immutable after registration, except for the
+		// interval and the timeout, which Reconfigure changes under the lock.
 		Check
-		healthy bool
-		msg     string
-		pos     uint
+
+		// The registration of the check, immutable after registration. The contents of the
+		// latencies and the history are guarded by the lock.
+		since     time.Time
+		pos       uint
+		bits      *healthStatus
+		group     *healthGroup
+		follow    *Doctor
+		latencies *latencies
+		history   *ring[CheckResult]
+		ctx       context.Context
+		cancel    context.CancelFunc
+
+		// The signals of the probe loop, immutable after registration
+		probing    chan struct{}
+		beats      chan struct{}
+		resume     chan struct{}
+		reschedule chan struct{}
+		exited     chan struct{}
+
+		// The state of the check, guarded by the lock
+		healthy     bool
+		starting    bool
+		msg         string
+		err         error
+		source      string
+		warning     string
+		detail      map[string]any
+		code        string
+		hint        string
+		failures    int
+		successes   int
+		probes      uint64
+		errors      uint64
+		timeouts    uint64
+		timeoutRun  int
+		timedOut    bool
+		breaker     string
+		opened      time.Time
+		recovering  time.Time
+		lastRun     time.Time
+		lastSuccess time.Time
+		lastFailure time.Time
+		duration    time.Duration
+		generation  uint64
+		disabled    bool
+		skipped     bool
+		interrupted bool
+		flight      *flight
+		due         time.Time
 		sync.RWMutex
 	}
 
 	// healthChecks is a sync-list of all health-states
 	healthChecks struct {
 		sync.RWMutex
-		items map[string]*healthCheckStatus
+		items  map[string]*healthCheckStatus
+		groups map[string]*healthGroup
+		next   uint
+		vacant []uint
+
+		// set by Stop, no checks are registered afterwards
+		stopped bool
 	}
 
-	// HealthStatus holds the status of all the healthchecks
+	// HealthStatus holds the status of all the healthchecks, one bit per check. The words grow
+	// when checks are added on higher positions. The number of set bits is kept aside, so the
+	// hot Healthy path is a single load whatever the number of checks.
 	healthStatus struct {
-		sync.RWMutex
-		status uint64
+		words atomic.Pointer[[]*atomic.Uint64]
+		set   atomic.Int64
+	}
+
+	// flight is a probe in flight, which forced probes of the same check join rather than running
+	// the handler again
+	flight struct {
+		done      chan struct{}
+		err       error
+		abandoned bool
 	}
 )
 
 // NewDoctor creates a new doctor
-func NewDoctor() *Doctor {
-	return &Doctor{
-		checks: &healthChecks{items: make(map[string]*healthCheckStatus)},
-		status: &healthStatus{status: 0},
+func NewDoctor(opts ...Option) *Doctor {
+	health := &Doctor{
+		ctx: context.Background(),
+		checks: &healthChecks{
+			items:  make(map[string]*healthCheckStatus),
+			groups: make(map[string]*healthGroup),
+		},
+		status:    &healthStatus{},
+		optional:  &healthStatus{},
+		watchers:  &watchers{items: make(map[int]func(string, bool))},
+		changes:   &listeners{items: make(map[int]func())},
+		notifiers: &notifiers{items: make(map[int]*notifier)},
+		events:    &events{ch: make(chan Event, eventBuffer)},
+		clock:     realClock{},
+		interval:  DefaultInterval,
+		timeout:   DefaultTimeout,
+
+		healthyStatus:   http.StatusOK,
+		unhealthyStatus: http.StatusServiceUnavailable,
+	}
+	for _, opt := range opts {
+		opt(health)
 	}
+	health.up.Store(true)
+	if health.started.IsZero() {
+		health.started = health.clock.Now()
+	}
+	health.halt, health.stop = context.WithCancel(health.ctx)
+	if health.file != nil {
+		health.persist()
+	}
+	if health.staleness > 0 && !health.manual {
+		health.wg.Add(1)
+		go health.watch()
+	}
+	return health
 }
 
 // Investigate checks if a certain check is good or not. The health-check should not block and may not take
-// longer than its timeout to finish.
-func (health *Doctor) Investigate(ctx context.Context, healthCheck *Check) error {
+// longer than its timeout to finish. The check runs until it is removed, the doctor is stopped or
+// the root context of the doctor is done.
+func (health *Doctor) Investigate(healthCheck *Check) error {
+	return health.InvestigateCtx(health.ctx, healthCheck)
+}
+
+// InvestigateCtx is like Investigate, but ties the check to the given context as well. The check
+// stops when either this context or the root context is done.
+func (health *Doctor) InvestigateCtx(ctx context.Context, healthCheck *Check) error {
+	return health.register(ctx, healthCheck, nil)
+}
+
+// register a check, following the given doctor instead of probing when not nil
+func (health *Doctor) register(ctx context.Context, healthCheck *Check, follow *Doctor) error {
+	config, err := health.prepare(healthCheck)
+	if err != nil {
+		return err
+	}
+
+	check, err := func() (*healthCheckStatus, error) {
+		health.checks.Lock()
+		defer health.checks.Unlock()
+		if err := health.admit(config, nil); err != nil {
+			return nil, err
+		}
+		return health.add(ctx, config, follow), nil
+	}()
+	if err != nil {
+		return err
+	}
+	health.aggregate()
+	health.launch(check)
+	return nil
+}
+
+// InvestigateAll registers a batch of checks at once, like InvestigateCtx. The whole batch is
+// validated up front and registered under a single lock: either all checks are registered, or
+// none and the combined error is returned.
+func (health *Doctor) InvestigateAll(ctx context.Context, healthChecks ...*Check) error {
+	var errs []error
+	configs := make([]Check, 0, len(healthChecks))
+	for _, healthCheck := range healthChecks {
+		config, err := health.prepare(healthCheck)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		configs = append(configs, config)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	checks, err := func() ([]*healthCheckStatus, error) {
+		health.checks.Lock()
+		defer health.checks.Unlock()
+		pending := make(map[string][]string, len(configs))
+		for _, config := range configs {
+			if err := health.admit(config, pending); err != nil {
+				errs = append(errs, err)
+			}
+			pending[config.Name] = config.DependsOn
+		}
+		if len(errs) > 0 {
+			return nil, errors.Join(errs...)
+		}
+		checks := make([]*healthCheckStatus, 0, len(configs))
+		for _, config := range configs {
+			checks = append(checks, health.add(ctx, config, nil))
+		}
+		return checks, nil
+	}()
+	if err != nil {
+		return err
+	}
+	health.aggregate()
+	health.launch(checks...)
+	return nil
+}
+
+// prepare a copy of the check with the defaults applied, and validate it
+func (health *Doctor) prepare(healthCheck *Check) (Check, error) {
+	config := *healthCheck
+	if config.Interval == 0 {
+		config.Interval = health.interval
+	}
+	if config.Timeout == 0 {
+		config.Timeout = health.timeout
+	}
+	if config.IntervalJitter > 0 && config.Rand == nil {
+		config.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	if config.Handler == nil && config.Results == nil && config.TTL <= 0 {
+		return config, fmt.Errorf("health-check %q: a push check needs a TTL", config.Name)
+	}
+	if config.Once && config.Handler == nil {
+		return config, fmt.Errorf("health-check %q: only a probed check can be probed once", config.Name)
+	}
+	if config.Interval <= 0 || config.Timeout <= 0 {
+		return config, fmt.Errorf("health-check %q: interval %s and timeout %s must be positive", config.Name, config.Interval, config.Timeout)
+	}
+	if config.Timeout >= config.Interval {
+		return config, fmt.Errorf("health-check %q: timeout %s must be shorter than interval %s", config.Name, config.Timeout, config.Interval)
+	}
+	if config.IntervalJitter >= config.Interval {
+		return config, fmt.Errorf("health-check %q: jitter %s must be shorter than interval %s", config.Name, config.IntervalJitter, config.Interval)
+	}
+	return config, nil
+}
+
+// admit validates a check against the registered checks and the pending checks of a batch,
+// with their dependencies. The checks must be locked.
+func (health *Doctor) admit(config Check, pending map[string][]string) error {
+	if health.checks.stopped {
+		return fmt.Errorf("health-check %q: %w", config.Name, ErrStopped)
+	}
+	if _, ok := health.checks.items[config.Name]; ok {
+		return fmt.Errorf("health-check %q already registered", config.Name)
+	}
+	if _, ok := pending[config.Name]; ok {
+		return fmt.Errorf("health-check %q registered twice", config.Name)
+	}
+	if health.maxChecks > 0 && len(health.checks.items)+len(pending) >= health.maxChecks {
+		return fmt.Errorf("health-check threshold (%d) exceeded", health.maxChecks)
+	}
+	if _, ok := health.checks.groups[config.Group]; config.Group != "" && !ok {
+		return fmt.Errorf("health-check %q: group %q not found", config.Name, config.Group)
+	}
+	if health.checks.cyclic(config.Name, config.DependsOn, pending) {
+		return fmt.Errorf("health-check %q: dependency cycle", config.Name)
+	}
+	return nil
+}
+
+// add an admitted check to the list of checks. The checks must be locked.
+func (health *Doctor) add(ctx context.Context, config Check, follow *Doctor) *healthCheckStatus {
+	if config.Detached {
+		ctx = context.WithoutCancel(ctx)
+	}
+	pos := health.checks.alloc()
+	ctx, cancelCtx := context.WithCancel(ctx)
+	unlink := context.AfterFunc(health.halt, cancelCtx)
+	cancel := func() {
+		unlink()
+		cancelCtx()
+	}
+	check := &healthCheckStatus{
+		Check:      config,
+		healthy:    false,
+		msg:        "[n/a]",
+		pos:        pos,
+		bits:       health.status,
+		ctx:        ctx,
+		cancel:     cancel,
+		since:      health.clock.Now(),
+		probing:    make(chan struct{}, 1),
+		beats:      make(chan struct{}, 1),
+		resume:     make(chan struct{}, 1),
+		reschedule: make(chan struct{}, 1),
+		exited:     make(chan struct{}),
+		follow:     follow,
+	}
+	if health.window > 0 {
+		check.latencies = newLatencies(health.window)
+	}
+	if health.results > 0 {
+		check.history = newRing[CheckResult](health.results)
+	}
+	if check.Group != "" {
+		check.group = health.checks.groups[check.Group]
+		check.bits = check.group.bits
+	} else if check.Severity == NonCritical {
+		check.bits = health.optional
+	}
+	if check.BreakerThreshold > 0 {
+		check.breaker = BreakerClosed
+	}
+	if check.InitialDelay > 0 {
+		check.starting = true
+		check.msg = ""
+	}
+	health.checks.items[config.Name] = check
+	check.bits.update(pos, check.starting)
+	if check.group != nil {
+		check.group.join(1, health.status)
+	}
+	health.wg.Add(1)
+	return check
+}
+
+// launch the probe loops of added checks. With WithSyncFirstProbe, the first probes of the
+// probed checks without an initial delay run first, and are waited for.
+func (health *Doctor) launch(checks ...*healthCheckStatus) {
+	probed := make([]bool, len(checks))
+	if health.sync {
+		health.prime(checks, probed)
+	}
+	for i, check := range checks {
+		if health.manual {
+			check.schedule(health.clock.Now().Add(check.delay(health, probed[i])))
+		}
+		go func(check *healthCheckStatus, probed bool) {
+			defer health.wg.Done()
+			defer close(check.exited)
+			check.start(health, probed)
+		}(check, probed[i])
+	}
+}
+
+// prime runs the first probes of the probed checks without an initial delay, concurrently but
+// dependencies before their dependents, so a dependent is not skipped for a dependency which
+// has no result yet. It marks the checks whose handler ran; the others are scheduled as if
+// there was no first probe.
+func (health *Doctor) prime(checks []*healthCheckStatus, probed []bool) {
+	waiting := make(map[string]int)
+	for i, check := range checks {
+		if check.Handler != nil && check.follow == nil && check.InitialDelay <= 0 {
+			waiting[check.Name] = i
+		}
+	}
+	for len(waiting) > 0 {
+		// the batch has no cycles, so every round probes at least one check
+		var round []int
+		for _, i := range waiting {
+			if !waits(checks[i], waiting) {
+				round = append(round, i)
+			}
+		}
+		var wg sync.WaitGroup
+		for _, i := range round {
+			delete(waiting, checks[i].Name)
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				probed[i], _ = checks[i].check(health)
+			}(i)
+		}
+		wg.Wait()
+	}
+}
+
+// waits tells whether a dependency of the check still waits for its first probe
+func waits(hc *healthCheckStatus, waiting map[string]int) bool {
+	for _, dependency := range hc.DependsOn {
+		if _, ok := waiting[dependency]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove deregisters a health-check. Its probe loop is stopped and its position is released,
+// so it can be reused by a next Investigate.
+func (health *Doctor) Remove(name string) error {
+	if err := health.remove(name, nil); err != nil {
+		return err
+	}
+	health.aggregate()
+	return nil
+}
+
+// remove a check from the list of checks. When only is not nil, the check is only removed if it
+// is still the registered one.
+func (health *Doctor) remove(name string, only *healthCheckStatus) error {
 	health.checks.Lock()
 	defer health.checks.Unlock()
-	pos := uint(len(health.checks.items))
-	if pos < 63 {
-		check := &healthCheckStatus{
-			Check:   *healthCheck,
-			healthy: false,
-			msg:     "[n/a]",
-			pos:     pos,
-		}
-		health.checks.items[healthCheck.Name] = check
-		health.status.update(pos, false)
-		go check.start(ctx, health.status)
-	} else {
-		return errors.New("health-check treshold (64) exceeded")
+	check, ok := health.checks.items[name]
+	if !ok || (only != nil && check != only) {
+		return fmt.Errorf("health-check %q not found", name)
+	}
+	delete(health.checks.items, name)
+
+	// cancel and clear under the check lock, so a probe in flight cannot set the bit again
+	check.Lock()
+	defer check.Unlock()
+	check.cancel()
+	check.bits.update(check.pos, true)
+	health.checks.release(check.pos)
+	if check.group != nil {
+		check.group.join(-1, health.status)
 	}
 	return nil
 }
 
-// Healthy return if the service is healty or not (true/false)
+// RunCheck probes a health-check immediately and returns the result of the handler. The stored
+// state is updated as with a scheduled probe, which keeps running unaffected. When a probe is in
+// flight, scheduled or forced, RunCheck joins it and returns its result instead of running the
+// handler again.
+func (health *Doctor) RunCheck(ctx context.Context, name string) error {
+	health.checks.RLock()
+	check, ok := health.checks.items[name]
+	health.checks.RUnlock()
+	if !ok {
+		return fmt.Errorf("health-check %q not found", name)
+	}
+	return check.run(ctx, health)
+}
+
+// Stop cancels all running health-checks and waits until their probe loops have exited. The
+// health state is frozen afterwards: Healthy and Handler keep reporting the last known state,
+// and registering a check fails with ErrStopped.
+func (health *Doctor) Stop() {
+	func() {
+		// no check is added, and no probe loop joins the wait group, once stopped is set
+		health.checks.Lock()
+		defer health.checks.Unlock()
+		health.checks.stopped = true
+		for _, check := range health.checks.items {
+			check.cancel()
+		}
+	}()
+	health.stop()
+	health.wg.Wait()
+	health.events.close()
+}
+
+// Healthy return if the service is healty or not (true/false). Failing non-critical checks do
+// not make the service unhealthy. With a health policy, the policy decides. While forced with
+// SetUnhealthy, it is false whatever the checks. Without a policy it takes no lock, so it is
+// cheap enough for every accepted connection.
 func (health *Doctor) Healthy() bool {
-	health.status.RLock()
-	defer health.status.RUnlock()
-	return health.status.status == 0
+	if health.override.Load() != nil {
+		return false
+	}
+	if health.policy != nil {
+		return health.policy(health.Status())
+	}
+	return health.status.clear()
 }
 
-// start the health check. We use the time.After method instead of Tick to avoid
+// Reason returns a summary of the failing checks with their messages, sorted by name, e.g. for
+// logging why the service is unhealthy. The reason of SetUnhealthy comes first. It is empty when
+// no check fails.
+func (health *Doctor) Reason() string {
+	failing := health.checks.failing()
+	names := make([]string, 0, len(failing))
+	for name := range failing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	reasons := make([]string, 0, len(names)+1)
+	if override := health.override.Load(); override != nil {
+		reasons = append(reasons, "forced unhealthy: "+*override)
+	}
+	for _, name := range names {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", name, failing[name]))
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// Degraded returns true when one or more non-critical checks fail
+func (health *Doctor) Degraded() bool {
+	return !health.optional.clear()
+}
+
+// start the health check. We use the After method of the clock instead of a ticker to avoid
 // having a stack overflow when health-check do not end in a timely manner
-func (hc *healthCheckStatus) start(ctx context.Context, status *healthStatus) {
-	check := func() {
-		subctx, cancel := context.WithTimeout(ctx, hc.Timeout)
-		go func() {
-			defer cancel()
-			err := hc.Handler(subctx)
-			if hc.Aspect != nil {
-				err = hc.Aspect(hc.Check, err)
-			}
-			hc.Lock()
-			defer hc.Unlock()
-			if err == nil {
-				status.update(hc.pos, true)
-				hc.healthy = true
-				hc.msg = ""
-			} else {
-				status.update(hc.pos, false)
-				hc.healthy = false
-				hc.msg = err.Error()
-			}
+func (hc *healthCheckStatus) start(health *Doctor, probed bool) {
+	if hc.Results != nil {
+		hc.receive(health)
+		return
+	}
+	if hc.Handler == nil {
+		hc.expire(health)
+		return
+	}
+	if hc.follow != nil {
+		hc.embed(health)
+		return
+	}
+	if health.manual {
+		// probed on demand only, see WithoutScheduler
+		return
+	}
 
-		}()
-		<-subctx.Done()
+	if probed && hc.settled() {
+		return
+	}
+	// the first probe waits for the clock even without a delay, so a fake clock holds it back
+	select {
+	case <-hc.ctx.Done():
+		return
+	case <-health.clock.After(hc.delay(health, probed)):
 	}
 
 	for {
-		check()
+		if !hc.await() || !health.acquire(hc.ctx) {
+			return
+		}
+		_, _ = hc.check(health)
+		health.release()
 
-		select {
-		case <-ctx.Done():
+		if hc.settled() || !hc.wait(health) {
 			return
-		case <-time.After(hc.Interval):
+		}
+	}
+}
+
+// settled tells whether a check which is probed once has its result
+func (hc *healthCheckStatus) settled() bool {
+	if !hc.Once {
+		return false
+	}
+	hc.RLock()
+	defer hc.RUnlock()
+	return !hc.lastRun.IsZero()
+}
+
+// delay returns the delay of the first scheduled probe: the initial delay, offset within the
+// jitter, or the interval when the first probe already ran on registration
+func (hc *healthCheckStatus) delay(health *Doctor, probed bool) time.Duration {
+	if probed {
+		return hc.interval(health.clock.Now())
+	}
+	delay := hc.InitialDelay
+	if hc.IntervalJitter > 0 {
+		delay += time.Duration(hc.Rand.Int63n(int64(hc.IntervalJitter)))
+	}
+	return delay
+}
+
+// wait for the next probe. A reconfiguration restarts the wait with the new interval, Enable
+// ends it right away. It returns false when the check is done.
+func (hc *healthCheckStatus) wait(health *Doctor) bool {
+	for {
+		select {
+		case <-hc.ctx.Done():
+			return false
+		case <-hc.reschedule:
 			continue
+		case <-hc.resume:
+			return true
+		case <-health.clock.After(hc.interval(health.clock.Now())):
+			return true
 		}
 	}
 }
 
-// update the health check status on a given position
-func (c *healthStatus) update(pos uint, value bool) {
-	c.Lock()
-	defer c.Unlock()
-	if !value {
-		c.status |= (1 << pos)
-	} else {
-		c.status &= ^(1 << pos)
+// check runs a scheduled probe, unless a dependency is unhealthy. It returns whether the handler
+// ran, and its result.
+func (hc *healthCheckStatus) check(health *Doctor) (bool, error) {
+	if dependency := health.checks.blocking(hc); dependency != "" {
+		hc.skip(health, dependency)
+		return false, nil
+	}
+	if hc.tripped(health.clock.Now()) {
+		// leave the dependency alone until the cooldown passed
+		return false, nil
 	}
+	own, join := hc.takeoff()
+	switch {
+	case own != nil:
+		err := hc.execute(hc.ctx, health)
+		hc.land(hc.ctx, own, err)
+		return true, err
+	case join != nil:
+		// a forced probe is in flight, it records its result anyway
+	default:
+		// the previous probe ignores its context and is still running, do not pile up
+		// another goroutine next to it
+		hc.apply(health, hc.next(), errors.New("previous probe still running"), health.clock.Now(), 0, "")
+	}
+	return false, nil
 }
 
-// Handler renders the health status page
-func (health *Doctor) Handler(w http.ResponseWriter, r *http.Request) {
-	var status = struct {
-		Status string            `json:"status"`
-		Errors map[string]string `json:"errors,omitempty"`
-	}{}
+// acquire a slot of the probe limit, waiting while all slots are taken. It returns false when
+// the context is done first.
+func (health *Doctor) acquire(ctx context.Context) bool {
+	if health.probes == nil {
+		return true
+	}
+	select {
+	case health.probes <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
 
-	var statusCode int
-	func() {
-		health.status.RLock()
-		defer health.status.RUnlock()
-		if health.status.status == 0 {
-			statusCode = http.StatusOK
-			status.Status = "up"
-		} else {
-			statusCode = http.StatusServiceUnavailable
-			status.Status = "down"
-			status.Errors = health.checks.failing()
+// release a slot of the probe limit
+func (health *Doctor) release() {
+	if health.probes != nil {
+		<-health.probes
+	}
+}
+
+// await blocks while the check is disabled. It returns false when the check is done.
+func (hc *healthCheckStatus) await() bool {
+	for {
+		hc.RLock()
+		disabled := hc.disabled
+		hc.RUnlock()
+		if !disabled {
+			return hc.ctx.Err() == nil
+		}
+		select {
+		case <-hc.ctx.Done():
+			return false
+		case <-hc.resume:
+		}
+	}
+}
+
+// unknown tells whether the check has no result yet, the check must be locked
+func (hc *healthCheckStatus) unknown() bool {
+	return hc.lastRun.IsZero() && !hc.starting
+}
+
+// failing tells whether the check counts as failing, the check must be locked
+func (hc *healthCheckStatus) failing() bool {
+	return !hc.healthy && !hc.starting && !hc.disabled && !hc.skipped
+}
+
+// run a probe on demand. A probe in flight is joined instead of repeated, so concurrent forced
+// probes and a scheduled one run the handler once and share its result.
+func (hc *healthCheckStatus) run(ctx context.Context, health *Doctor) error {
+	if hc.Handler == nil {
+		return fmt.Errorf("health-check %q: %w", hc.Name, ErrPushCheck)
+	}
+
+	for {
+		own, join := hc.takeoff()
+		switch {
+		case own != nil:
+			err := hc.execute(ctx, health)
+			hc.land(ctx, own, err)
+			return err
+		case join != nil:
+			select {
+			case <-join.done:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if join.abandoned && ctx.Err() == nil {
+				// the caller of the joined probe gave up, not ours
+				continue
+			}
+			return join.err
+		}
+
+		// the slot is held by a handler which outlived its probe, wait for it
+		select {
+		case hc.probing <- struct{}{}:
+			own := hc.board()
+			err := hc.execute(ctx, health)
+			hc.land(ctx, own, err)
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// takeoff takes the probing slot without waiting and registers the probe in flight. When another
+// probe is in flight, it returns that one to join instead. It returns neither when the slot is
+// held by a handler which outlived its probe.
+func (hc *healthCheckStatus) takeoff() (own, join *flight) {
+	hc.Lock()
+	defer hc.Unlock()
+	if hc.flight != nil {
+		return nil, hc.flight
+	}
+	select {
+	case hc.probing <- struct{}{}:
+		hc.flight = &flight{done: make(chan struct{})}
+		return hc.flight, nil
+	default:
+		return nil, nil
+	}
+}
+
+// board registers the probe in flight once the probing slot is taken
+func (hc *healthCheckStatus) board() *flight {
+	hc.Lock()
+	defer hc.Unlock()
+	hc.flight = &flight{done: make(chan struct{})}
+	return hc.flight
+}
+
+// land hands the result of the probe to the probes which joined it, with whether the caller gave
+// up on it
+func (hc *healthCheckStatus) land(ctx context.Context, f *flight, err error) {
+	hc.Lock()
+	hc.flight = nil
+	hc.Unlock()
+	f.err, f.abandoned = err, ctx.Err() != nil
+	close(f.done)
+}
+
+// interval returns the interval until the next probe, backed off while failing and randomized
+// by the jitter. A recovering check is probed again when its stabilization window ends, if that
+// comes first.
+func (hc *healthCheckStatus) interval(now time.Time) time.Duration {
+	interval := hc.backoff()
+	if hc.IntervalJitter > 0 {
+		interval += time.Duration(hc.Rand.Int63n(2*int64(hc.IntervalJitter)+1)) - hc.IntervalJitter
+	}
+	if window, ok := hc.window(now); ok && window < interval {
+		interval = window
+	}
+	if interval < 0 {
+		return 0
+	}
+	return interval
+}
+
+// window returns the time left in the stabilization window of a recovering check
+func (hc *healthCheckStatus) window(now time.Time) (time.Duration, bool) {
+	if hc.Stabilization <= 0 {
+		return 0, false
+	}
+	hc.RLock()
+	defer hc.RUnlock()
+	if hc.healthy || hc.successes == 0 {
+		return 0, false
+	}
+	return hc.Stabilization - now.Sub(hc.recovering), true
+}
+
+// timeout returns the timeout of the probes, which may be reconfigured
+func (hc *healthCheckStatus) timeout() time.Duration {
+	hc.RLock()
+	defer hc.RUnlock()
+	return hc.Timeout
+}
+
+// backoff returns the base interval, multiplied by the backoff factor for every consecutive
+// failure after the first one while the check is unhealthy
+func (hc *healthCheckStatus) backoff() time.Duration {
+	hc.RLock()
+	base, healthy, failures := hc.Interval, hc.healthy, hc.failures
+	hc.RUnlock()
+	if hc.BackoffFactor <= 1 {
+		return base
+	}
+
+	interval := float64(base)
+	for i := 1; !healthy && i < failures; i++ {
+		interval *= hc.BackoffFactor
+		if hc.MaxBackoff > 0 && interval >= float64(hc.MaxBackoff) {
+			return hc.MaxBackoff
+		}
+		if interval >= math.MaxInt64 {
+			return math.MaxInt64
+		}
+	}
+	return time.Duration(interval)
+}
+
+// execute a probe bound by the timeout of the check and return its result. The probing slot
+// must be taken; it is released when the handler returns, which may be well after the timeout
+// when the handler ignores its context. A probe which times out is recorded as failed right
+// away, its late result is discarded.
+func (hc *healthCheckStatus) execute(ctx context.Context, health *Doctor) error {
+	parent := identify(ctx)
+	if hc.Detached {
+		// only the timeout and the check itself bound the probe
+		parent = context.WithoutCancel(parent)
+	}
+	if hc.ContextFunc != nil {
+		parent = hc.ContextFunc(parent)
+	}
+	subctx, cancel := health.clock.WithTimeout(parent, hc.timeout())
+	unlink := context.AfterFunc(hc.ctx, cancel)
+	gen, begin := hc.next(), health.clock.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer cancel()
+		defer unlink()
+		defer func() { <-hc.probing }()
+		done <- hc.probe(subctx, health, gen)
+	}()
+
+	select {
+	case err := <-done:
+		hc.count(subctx, err)
+		return err
+	case <-subctx.Done():
+		select {
+		case err := <-done:
+			hc.count(subctx, err)
+			return err
+		default:
+		}
+		if ctx.Err() == nil {
+			// the probe itself timed out, not the caller
+			hc.count(subctx, subctx.Err())
+			timeout := &timeoutError{timeout: hc.timeout(), err: subctx.Err()}
+			hc.apply(health, hc.next(), timeout, begin, health.clock.Now().Sub(begin), ProbeID(subctx))
+		}
+		return subctx.Err()
+	}
+}
+
+// count a finished probe in the totals. A probe which ran out of its timeout counts as timeout,
+// a canceled one only as probe.
+func (hc *healthCheckStatus) count(ctx context.Context, err error) {
+	hc.Lock()
+	defer hc.Unlock()
+	hc.probes++
+	switch {
+	case err == nil:
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		hc.timeouts++
+	case !errors.Is(ctx.Err(), context.Canceled):
+		hc.errors++
+	}
+}
+
+// probe runs the handler and the aspect and applies their result
+func (hc *healthCheckStatus) probe(ctx context.Context, health *Doctor, gen uint64) error {
+	begin := health.clock.Now()
+	cause, err, took := hc.examine(ctx, health, begin)
+	result := err
+	switch {
+	case failure(err) != nil && errors.Is(ctx.Err(), context.DeadlineExceeded):
+		// the handler gave up because the probe ran out of time
+		result = &timeoutError{timeout: hc.timeout(), err: err}
+	case err != nil && !errors.Is(err, cause):
+		// the aspect failed the probe rather than the handler
+		result = &aspectError{err}
+	}
+	err = failure(err)
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		// the probe was canceled rather than failed, e.g. at shutdown, do not blame the dependency
+		hc.interrupt(gen)
+		return err
+	}
+	hc.apply(health, gen, result, begin, took, ProbeID(ctx))
+	if health.logger != nil {
+		health.logger.Debug("health-check probed", "name", hc.Name, "probe", ProbeID(ctx), "duration", took, "error", err)
+	}
+	return err
+}
+
+// examine runs the handler and passes its result through the aspects. It returns the result of
+// the handler, the one of the aspects and the duration of the handler.
+func (hc *healthCheckStatus) examine(ctx context.Context, health *Doctor, begin time.Time) (cause, err error, took time.Duration) {
+	err = hc.attempt(ctx, health)
+	took = health.clock.Now().Sub(begin)
+	cause = err
+	if hc.Aspect != nil {
+		hc.RLock()
+		config := hc.Check
+		hc.RUnlock()
+		err = protect(func() error {
+			return hc.Aspect(config, err)
+		})
+	}
+	if hc.ContextAspect != nil {
+		hc.RLock()
+		aspect := AspectContext{Check: hc.Check, Err: err, Duration: took, Failures: hc.failures, Healthy: hc.healthy}
+		hc.RUnlock()
+		err = protect(func() error {
+			return hc.ContextAspect(aspect)
+		})
+	}
+	return cause, err, took
+}
+
+// attempt runs the handler, retrying it on failure as configured. The retries stop when the
+// context is done, so they cannot exceed the timeout of the probe.
+func (hc *healthCheckStatus) attempt(ctx context.Context, health *Doctor) error {
+	err := protect(func() error {
+		return hc.Handler(ctx)
+	})
+	for i := 0; i < hc.Retries && failure(err) != nil && ctx.Err() == nil; i++ {
+		if !health.sleep(ctx, hc.RetryDelay) {
+			return err
+		}
+		err = protect(func() error {
+			return hc.Handler(ctx)
+		})
+	}
+	return err
+}
+
+// interrupt marks the check as interrupted, unless a newer result is recorded meanwhile. The
+// state of the check is kept as it was.
+func (hc *healthCheckStatus) interrupt(gen uint64) {
+	hc.Lock()
+	defer hc.Unlock()
+	if gen == hc.generation {
+		hc.interrupted = true
+	}
+}
+
+// protect runs fn and converts a panic into an error, so a broken handler cannot take the probe
+// loop down
+func protect(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
 		}
 	}()
+	return fn()
+}
+
+// next starts a new generation of results, so results of older generations are discarded
+func (hc *healthCheckStatus) next() uint64 {
+	hc.Lock()
+	defer hc.Unlock()
+	hc.generation++
+	return hc.generation
+}
+
+// apply the result of a probe to the check and the status. The probe ID is logged with the
+// result, it is empty for results which are not probed. The state change callback and the
+// watchers are called outside the lock, so they may query the doctor.
+func (hc *healthCheckStatus) apply(health *Doctor, gen uint64, err error, at time.Time, took time.Duration, probe string) {
+	healthy, changed, first, failures := hc.record(gen, err, at, took)
+	attrs := []any{"name", hc.Name}
+	if probe != "" {
+		attrs = append(attrs, "probe", probe)
+	}
+	var timeout *timeoutError
+	if errors.As(err, &timeout) && health.logger != nil {
+		hc.RLock()
+		timeouts := hc.timeoutRun
+		hc.RUnlock()
+		if timeouts == timeoutHint {
+			health.logger.Warn("health-check keeps timing out, its timeout may be too short", append(attrs, "timeout", timeout.timeout)...)
+		}
+	}
+	err = failure(err)
+	hc.regroup(health)
+	if changed {
+		if health.logger != nil {
+			if healthy {
+				health.logger.Info("health-check recovered", attrs...)
+			} else {
+				health.logger.Warn("health-check failing", append(attrs, "error", err, "failures", failures)...)
+			}
+		}
+		if hc.OnStateChange != nil {
+			hc.OnStateChange(hc.Name, healthy, err)
+		}
+		health.watchers.notify(hc.Name, healthy)
+		health.notifiers.notify(hc.Name, healthy, err)
+		health.events.emit(Event{Check: hc.Name, Healthy: healthy, Time: at})
+		health.aggregate()
+	} else if first {
+		// the check is ready, though not healthy yet, see WaitReady
+		health.changes.notify()
+	}
+}
 
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(statusCode)
-	_ = json.NewEncoder(w).Encode(status)
+// record the result of a probe and return the new state, whether it changed, whether it is the
+// first success and the number of consecutive failures. The first result of a check is a
+// change, unless it is a success which does not reach the success threshold yet.
+func (hc *healthCheckStatus) record(gen uint64, err error, at time.Time, took time.Duration) (healthy, changed, first bool, failures int) {
+	hc.Lock()
+	defer hc.Unlock()
+	if hc.ctx.Err() != nil && gen == hc.generation {
+		// the check was removed or stopped while probing
+		hc.interrupted = true
+	}
+	if hc.ctx.Err() != nil || gen != hc.generation || hc.disabled {
+		// the check was removed, stopped or disabled in the meantime, or a newer result is recorded
+		return hc.healthy, false, false, hc.failures
+	}
+	hc.interrupted = false
+	was, known := hc.healthy, !hc.lastRun.IsZero()
+	hc.detail = nil
+	var checkErr *CheckError
+	if errors.As(err, &checkErr) {
+		hc.detail = checkErr.Detail
+	}
+	hc.code, hc.hint = "", ""
+	var detailer HealthDetailer
+	if errors.As(err, &detailer) {
+		hc.code, hc.hint = detailer.Code(), detailer.Hint()
+	}
+	hc.warning = ""
+	var warning *Warning
+	if errors.As(err, &warning) {
+		hc.warning = warning.Error()
+	}
+	source := SourceHandler
+	var aspectErr *aspectError
+	if errors.As(err, &aspectErr) {
+		source, err = SourceAspect, aspectErr.err
+	}
+	var timeout *timeoutError
+	hc.timedOut = errors.As(err, &timeout)
+	if hc.timedOut {
+		hc.timeoutRun++
+	} else {
+		hc.timeoutRun = 0
+	}
+	err = failure(err)
+	hc.lastRun = at
+	hc.duration = took
+	if hc.latencies != nil {
+		hc.latencies.add(took)
+	}
+	if hc.history != nil {
+		result := CheckResult{Healthy: err == nil, Time: at, Duration: took}
+		if err != nil {
+			result.Message = err.Error()
+		}
+		hc.history.add(result)
+	}
+	if err == nil {
+		first = hc.lastSuccess.IsZero()
+		hc.lastSuccess = at
+		hc.failures = 0
+		hc.successes++
+		if hc.successes == 1 {
+			hc.recovering = at
+		}
+		if hc.healthy || hc.starting || hc.stable(at) {
+			hc.bits.update(hc.pos, true)
+			hc.healthy = true
+			hc.msg = ""
+			hc.err = nil
+			hc.source = ""
+		}
+	} else {
+		hc.lastFailure = at
+		hc.successes = 0
+		hc.failures++
+		if !hc.healthy || hc.failures >= threshold(hc.FailureThreshold) {
+			hc.bits.update(hc.pos, false)
+			hc.healthy = false
+			hc.msg = err.Error()
+			hc.err = err
+			hc.source = source
+		}
+	}
+	hc.trip(err != nil, at)
+	hc.starting = false
+	hc.skipped = false
+	if !known {
+		return hc.healthy, hc.healthy || err != nil, first, hc.failures
+	}
+	return hc.healthy, hc.healthy != was, first, hc.failures
+}
+
+// stable tells whether an unhealthy check passed the success threshold and the stabilization
+// window, the check must be locked
+func (hc *healthCheckStatus) stable(at time.Time) bool {
+	return hc.successes >= threshold(hc.SuccessThreshold) && at.Sub(hc.recovering) >= hc.Stabilization
+}
+
+// timeoutHint is the number of consecutive timeouts after which the doctor logs that the timeout
+// of the check may be too short
+const timeoutHint = 3
+
+// threshold returns the configured threshold, at least 1
+func threshold(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+// update the health check status on a given position. The bit is flipped with a CAS loop, so
+// concurrent updates of other bits in the same word are not lost.
+func (c *healthStatus) update(pos uint, value bool) {
+	index, bit := pos/64, uint64(1)<<(pos%64)
+	if value && index >= uint(len(c.load())) {
+		// never set, nothing to clear
+		return
+	}
+	word := c.word(index)
+	for {
+		old := word.Load()
+		next := old | bit
+		if value {
+			next = old &^ bit
+		}
+		if old == next {
+			return
+		}
+		if word.CompareAndSwap(old, next) {
+			// the bit of a position is only flipped under the lock of its owner, so the count
+			// follows the bits
+			if value {
+				c.set.Add(-1)
+			} else {
+				c.set.Add(1)
+			}
+			return
+		}
+	}
+}
+
+// load returns the current words
+func (c *healthStatus) load() []*atomic.Uint64 {
+	if words := c.words.Load(); words != nil {
+		return *words
+	}
+	return nil
+}
+
+// word returns the word with the given index, growing the words when needed. Grown words keep
+// the existing ones, so updates on them stay visible.
+func (c *healthStatus) word(index uint) *atomic.Uint64 {
+	for {
+		old := c.words.Load()
+		words := c.load()
+		if index < uint(len(words)) {
+			return words[index]
+		}
+		grown := make([]*atomic.Uint64, index+1)
+		copy(grown, words)
+		for i := len(words); i < len(grown); i++ {
+			grown[i] = new(atomic.Uint64)
+		}
+		if c.words.CompareAndSwap(old, &grown) {
+			return grown[index]
+		}
+	}
+}
+
+// hex returns the bits as hexadecimal string
+func (c *healthStatus) hex() string {
+	var b strings.Builder
+	for _, word := range c.load() {
+		fmt.Fprintf(&b, "%016x", word.Load())
+	}
+	return b.String()
+}
+
+// clear returns true when no bit is set
+func (c *healthStatus) clear() bool {
+	return c.set.Load() == 0
+}
+
+// reset drops all bits. Nothing may update them meanwhile.
+func (c *healthStatus) reset() {
+	c.words.Store(nil)
+	c.set.Store(0)
+}
+
+// alloc returns a vacated position if there is one, or a new one. Reusing positions keeps the
+// status bits bounded when checks come and go.
+func (checks *healthChecks) alloc() uint {
+	if n := len(checks.vacant); n > 0 {
+		pos := checks.vacant[n-1]
+		checks.vacant = checks.vacant[:n-1]
+		return pos
+	}
+	pos := checks.next
+	checks.next++
+	return pos
+}
+
+// release a position for reuse
+func (checks *healthChecks) release(pos uint) {
+	checks.vacant = append(checks.vacant, pos)
+}
+
+// evaluate the matching checks and return the aggregated state, together with the failing ones.
+// The members of a group only take the state down when the group misses its quorum. With
+// startup, checks without a result yet do not count as failing, and the state is starting
+// while there are any, unless the service is down anyway.
+func (checks *healthChecks) evaluate(match func(*healthCheckStatus) bool, startup bool) (string, map[string]string) {
+	checks.RLock()
+	defer checks.RUnlock()
+	state := statusUp
+	errors := make(map[string]string)
+	healthy := make(map[*healthGroup]int)
+	pending := false
+	for name, hc := range checks.items {
+		if !match(hc) {
+			continue
+		}
+		hc.RLock()
+		failing := hc.failing()
+		if startup && failing && hc.unknown() {
+			pending = true
+			hc.RUnlock()
+			continue
+		}
+		if failing {
+			errors[name] = hc.msg
+		}
+		switch {
+		case hc.group != nil:
+			if !failing {
+				healthy[hc.group]++
+			} else if _, ok := healthy[hc.group]; !ok {
+				healthy[hc.group] = 0
+			}
+		case failing && hc.Severity == Critical:
+			state = statusDown
+		case failing && state == statusUp:
+			state = statusDegraded
+		}
+		hc.RUnlock()
+	}
+	for group, n := range healthy {
+		if n < group.min {
+			state = statusDown
+		}
+	}
+	if pending && state != statusDown {
+		state = statusStarting
+	}
+	return state, errors
+}
+
+// selection returns the pull checks with the given names, or all pull checks when no names
+// are given. Unknown names are ignored.
+func (checks *healthChecks) selection(names []string) []*healthCheckStatus {
+	checks.RLock()
+	defer checks.RUnlock()
+	var selected []*healthCheckStatus
+	if len(names) == 0 {
+		for _, hc := range checks.items {
+			if hc.Handler != nil {
+				selected = append(selected, hc)
+			}
+		}
+		return selected
+	}
+	for _, name := range names {
+		if hc, ok := checks.items[name]; ok && hc.Handler != nil {
+			selected = append(selected, hc)
+		}
+	}
+	return selected
 }
 
 // make a map with failing health checks
@@ -179,11 +1499,11 @@ func (checks *healthChecks) failing() map[string]string {
 	defer checks.RUnlock()
 	errors := make(map[string]string)
 	for name, hc := range checks.items {
-		hc.Lock()
-		defer hc.Unlock()
-		if len(hc.msg) > 0 {
+		hc.RLock()
+		if hc.failing() {
 			errors[name] = hc.msg
 		}
+		hc.RUnlock()
 	}
 	return errors
 }
@@ -0,0 +1,274 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHandlerIgnoringContextDoesNotLeakGoroutines(t *testing.T) {
+	block := make(chan struct{})
+	health := NewDoctor()
+	t.Cleanup(func() {
+		close(block)
+		health.Stop()
+	})
+	err := health.Investigate(&Check{
+		Name:     "stuck",
+		Interval: 10 * time.Millisecond,
+		Timeout:  5 * time.Millisecond,
+		Handler: func(context.Context) error {
+			<-block
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	before := runtime.NumGoroutine()
+	time.Sleep(500 * time.Millisecond)
+	if after := runtime.NumGoroutine(); after > before+2 {
+		t.Fatalf("goroutines grew from %d to %d over many intervals", before, after)
+	}
+	if health.Healthy() {
+		t.Fatal("stuck check reported healthy")
+	}
+}
+
+func TestChurnKeepsStatusBitsBounded(t *testing.T) {
+	health := NewDoctor()
+	t.Cleanup(health.Stop)
+	ctx := context.Background()
+	for i := 0; i < 500; i++ {
+		name := fmt.Sprintf("job-%d", i)
+		err := health.Investigate(&Check{
+			Name:     name,
+			Interval: time.Hour,
+			Handler: func(context.Context) error {
+				return errors.New("down")
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		health.RunCheck(ctx, name)
+		if health.Healthy() {
+			t.Fatalf("%s: failing check reported healthy", name)
+		}
+		if err := health.Remove(name); err != nil {
+			t.Fatal(err)
+		}
+		if n := len(health.StatusBits()); n > 1 {
+			t.Fatalf("after %d checks came and went the status has %d words", i+1, n)
+		}
+	}
+	if !health.Healthy() {
+		t.Fatal("doctor unhealthy without checks")
+	}
+}
+
+func TestConcurrentStatusUpdates(t *testing.T) {
+	// each position is owned by one goroutine, as each bit is by one check, while the words are
+	// shared and grow concurrently
+	const positions = 200
+	var status healthStatus
+	var wg sync.WaitGroup
+	for pos := uint(0); pos < positions; pos++ {
+		wg.Add(1)
+		go func(pos uint) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				status.update(pos, i%2 == 1)
+			}
+			status.update(pos, pos%3 != 0)
+		}(pos)
+	}
+	wg.Wait()
+
+	words := status.load()
+	set := 0
+	for pos := uint(0); pos < positions; pos++ {
+		failing := words[pos/64].Load()&(1<<(pos%64)) != 0
+		if failing != (pos%3 == 0) {
+			t.Fatalf("position %d: failing %t", pos, failing)
+		}
+		if failing {
+			set++
+		}
+	}
+	if n := status.count(); n != set {
+		t.Fatalf("count %d, %d bits set", n, set)
+	}
+}
+
+func TestConcurrentReconfigureAndProbes(t *testing.T) {
+	health := NewDoctor()
+	t.Cleanup(health.Stop)
+	var calls atomic.Int64
+	err := health.Investigate(&Check{
+		Name:     "flaky",
+		Interval: 5 * time.Millisecond,
+		Timeout:  2 * time.Millisecond,
+		Handler: func(context.Context) error {
+			if n := calls.Add(1); n%2 == 0 {
+				return fmt.Errorf("failure %d", n)
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	run := func(fn func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				fn(i)
+			}
+		}()
+	}
+	run(func(i int) {
+		interval := time.Duration(5+i%5) * time.Millisecond
+		if err := health.Reconfigure("flaky", interval, interval/2); err != nil {
+			t.Error(err)
+		}
+	})
+	run(func(int) { health.RunCheck(ctx, "flaky") })
+	run(func(int) { _ = health.Status() })
+	run(func(int) { _ = health.Reason() })
+	wg.Wait()
+
+	if calls.Load() == 0 {
+		t.Fatal("check never probed")
+	}
+}
+
+// waiter is a context which tells when the first one waits for it to be done
+type waiter struct {
+	context.Context
+	once    sync.Once
+	waiting chan struct{}
+}
+
+func newWaiter(ctx context.Context) *waiter {
+	return &waiter{Context: ctx, waiting: make(chan struct{})}
+}
+
+func (w *waiter) Done() <-chan struct{} {
+	w.once.Do(func() { close(w.waiting) })
+	return w.Context.Done()
+}
+
+// probed registers a check without scheduler whose handler blocks on the given channel, and
+// counts the calls by probe ID
+func probed(t *testing.T, block <-chan struct{}, entered chan<- string) (*Doctor, func(string) int) {
+	t.Helper()
+	health := NewDoctor(WithoutScheduler())
+	t.Cleanup(health.Stop)
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	err := health.Investigate(&Check{
+		Name:     "db",
+		Interval: time.Hour,
+		Handler: func(ctx context.Context) error {
+			mu.Lock()
+			calls[ProbeID(ctx)]++
+			mu.Unlock()
+			entered <- ProbeID(ctx)
+			select {
+			case <-block:
+				return errors.New("down")
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return health, func(id string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls[id]
+	}
+}
+
+func TestForcedProbesJoinTheProbeInFlight(t *testing.T) {
+	block, entered := make(chan struct{}), make(chan string, 2)
+	health, calls := probed(t, block, entered)
+	ctx := context.Background()
+
+	first := make(chan error, 1)
+	go func() {
+		first <- health.RunCheck(WithProbeID(ctx, "a"), "db")
+	}()
+	<-entered
+	joiner := newWaiter(ctx)
+	second := make(chan error, 1)
+	go func() {
+		second <- health.RunCheck(WithProbeID(joiner, "b"), "db")
+	}()
+	<-joiner.waiting
+
+	// a scheduled probe which is due meanwhile joins as well, without waiting
+	if results := health.Step(ctx); len(results) != 0 {
+		t.Fatalf("scheduled probe ran next to the forced one: %v", results)
+	}
+	close(block)
+	a, b := <-first, <-second
+	if a == nil || a != b {
+		t.Fatalf("results %v and %v, want the shared failure", a, b)
+	}
+	if calls("a") != 1 || calls("b") != 0 {
+		t.Fatalf("handler ran %d times for a and %d for b, want once in total", calls("a"), calls("b"))
+	}
+	if health.Healthy() {
+		t.Fatal("failure of the shared probe not recorded")
+	}
+}
+
+func TestJoinerRetriesAnAbandonedProbe(t *testing.T) {
+	block, entered := make(chan struct{}), make(chan string, 2)
+	health, calls := probed(t, block, entered)
+	ctx := context.Background()
+
+	owner, give := context.WithCancel(ctx)
+	first := make(chan error, 1)
+	go func() {
+		first <- health.RunCheck(WithProbeID(owner, "a"), "db")
+	}()
+	<-entered
+	joiner := newWaiter(ctx)
+	second := make(chan error, 1)
+	go func() {
+		second <- health.RunCheck(WithProbeID(joiner, "b"), "db")
+	}()
+	<-joiner.waiting
+
+	// the owner gives up, the joiner runs a probe of its own rather than taking the cancellation
+	give()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("owner got %v, want canceled", err)
+	}
+	if id := <-entered; id != "b" {
+		t.Fatalf("probe %q ran, want the one of the joiner", id)
+	}
+	close(block)
+	if err := <-second; err == nil || errors.Is(err, context.Canceled) {
+		t.Fatalf("joiner got %v, want the failure of its own probe", err)
+	}
+	if calls("a") != 1 || calls("b") != 1 {
+		t.Fatalf("handler ran %d times for a and %d for b", calls("a"), calls("b"))
+	}
+}
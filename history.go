@@ -0,0 +1,36 @@
+package doctor
+
+import "time"
+
+// CheckResult is a recorded probe result of a check, see WithHistory
+type CheckResult struct {
+
+	// Whether the probe succeeded
+	Healthy bool
+
+	// When the probe started
+	Time time.Time
+
+	// The duration of the probe
+	Duration time.Duration
+
+	// The error message of a failed probe
+	Message string
+}
+
+// History returns the recent results of a check, the oldest first. It is empty without a
+// history size or for an unknown check.
+func (health *Doctor) History(name string) []CheckResult {
+	health.checks.RLock()
+	check, ok := health.checks.items[name]
+	health.checks.RUnlock()
+	if !ok {
+		return nil
+	}
+	check.RLock()
+	defer check.RUnlock()
+	if check.history == nil {
+		return nil
+	}
+	return check.history.list()
+}
@@ -0,0 +1,40 @@
+package doctor
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Latency holds percentiles of the recent probe durations of a check, see WithLatencyWindow
+type Latency struct {
+	P50 time.Duration
+	P95 time.Duration
+	P99 time.Duration
+}
+
+// latencies is a ring buffer of the recent probe durations
+type latencies struct {
+	*ring[time.Duration]
+}
+
+// newLatencies creates a ring buffer with room for size durations
+func newLatencies(size int) *latencies {
+	return &latencies{newRing[time.Duration](size)}
+}
+
+// percentiles returns the percentiles of the durations, nil when there are none yet
+func (l *latencies) percentiles() *Latency {
+	sorted := l.list()
+	n := len(sorted)
+	if n == 0 {
+		return nil
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	rank := func(p float64) time.Duration {
+		return sorted[int(math.Ceil(p*float64(n)))-1]
+	}
+	return &Latency{P50: rank(0.50), P95: rank(0.95), P99: rank(0.99)}
+}
@@ -0,0 +1,69 @@
+package doctor
+
+import (
+	"bufio"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// escaper escapes label values for the prometheus text format
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// MetricsHandler renders the health in the prometheus text format, without depending on the
+// prometheus client:
+//
+//	doctor_up                                     1 when the service is healthy, 0 otherwise
+//	doctor_check_healthy{name="..."}              1 when the check is healthy, 0 otherwise
+//	doctor_check_last_duration_seconds{name="..."} the duration of the last probe
+//	doctor_check_probes_total{name="..."}          the number of probes
+//	doctor_check_errors_total{name="..."}          the number of probes failed with an error
+//	doctor_check_timeouts_total{name="..."}        the number of probes which timed out
+func (health *Doctor) MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	statuses := health.Status()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	out := bufio.NewWriter(w)
+	defer out.Flush()
+
+	out.WriteString("# HELP doctor_up Whether the service is healthy (1) or not (0).\n")
+	out.WriteString("# TYPE doctor_up gauge\n")
+	out.WriteString("doctor_up " + gauge(health.Healthy()) + "\n")
+
+	out.WriteString("# HELP doctor_check_healthy Whether the health-check is healthy (1) or not (0).\n")
+	out.WriteString("# TYPE doctor_check_healthy gauge\n")
+	for _, status := range statuses {
+		out.WriteString(`doctor_check_healthy{name="` + escaper.Replace(status.Name) + `"} ` + gauge(status.Healthy) + "\n")
+	}
+
+	out.WriteString("# HELP doctor_check_last_duration_seconds The duration of the last health-check probe.\n")
+	out.WriteString("# TYPE doctor_check_last_duration_seconds gauge\n")
+	for _, status := range statuses {
+		duration := strconv.FormatFloat(status.Duration.Seconds(), 'g', -1, 64)
+		out.WriteString(`doctor_check_last_duration_seconds{name="` + escaper.Replace(status.Name) + `"} ` + duration + "\n")
+	}
+
+	counters := []struct {
+		name, help string
+		value      func(CheckStatus) uint64
+	}{
+		{"doctor_check_probes_total", "The number of health-check probes.", func(s CheckStatus) uint64 { return s.Probes }},
+		{"doctor_check_errors_total", "The number of health-check probes which failed with an error.", func(s CheckStatus) uint64 { return s.Errors }},
+		{"doctor_check_timeouts_total", "The number of health-check probes which timed out.", func(s CheckStatus) uint64 { return s.Timeouts }},
+	}
+	for _, counter := range counters {
+		out.WriteString("# HELP " + counter.name + " " + counter.help + "\n")
+		out.WriteString("# TYPE " + counter.name + " counter\n")
+		for _, status := range statuses {
+			out.WriteString(counter.name + `{name="` + escaper.Replace(status.Name) + `"} ` + strconv.FormatUint(counter.value(status), 10) + "\n")
+		}
+	}
+}
+
+// gauge formats a boolean as gauge value
+func gauge(value bool) string {
+	if value {
+		return "1"
+	}
+	return "0"
+}
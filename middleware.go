@@ -0,0 +1,59 @@
+package doctor
+
+import "net/http"
+
+type (
+	// middleware holds the configuration of the middleware
+	middleware struct {
+		bypass map[string]bool
+		status int
+		body   []byte
+	}
+
+	// MiddlewareOption configures the middleware
+	MiddlewareOption func(*middleware)
+)
+
+// WithBypass lets requests on the given paths pass while the service is unhealthy, e.g. the path
+// of a health endpoint mounted elsewhere than the conventional paths
+func WithBypass(paths ...string) MiddlewareOption {
+	return func(m *middleware) {
+		for _, path := range paths {
+			m.bypass[path] = true
+		}
+	}
+}
+
+// WithUnhealthyResponse sets the status code and body returned while the service is unhealthy.
+// The defaults are 503 and an empty body.
+func WithUnhealthyResponse(status int, body []byte) MiddlewareOption {
+	return func(m *middleware) {
+		m.status = status
+		m.body = body
+	}
+}
+
+// Middleware rejects requests while the service is unhealthy, so load balancers drain it without
+// touching the listener. The conventional paths of Routes always pass, so the health endpoints
+// keep reporting; add other paths of health endpoints with WithBypass.
+func (health *Doctor) Middleware(next http.Handler, opts ...MiddlewareOption) http.Handler {
+	m := &middleware{
+		bypass: make(map[string]bool),
+		status: http.StatusServiceUnavailable,
+	}
+	for _, path := range paths {
+		m.bypass[path] = true
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.bypass[r.URL.Path] || health.Healthy() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.WriteHeader(m.status)
+		_, _ = w.Write(m.body)
+	})
+}
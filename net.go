@@ -2,23 +2,154 @@ package doctor
 
 import (
 	"net"
+	"sync"
+	"time"
 )
 
-// Listener is a net.Listener which stops connections when the health check fails
-type Listener struct {
-	net.Listener
-	health *Doctor
+type (
+	// Listener is a net.Listener which stops connections when the health check fails
+	Listener struct {
+		net.Listener
+		health  *Doctor
+		accept  func(*Doctor) bool
+		idle    time.Duration
+		conns   *connections
+		drain   *drain
+		stop    bool
+		open    bool
+		closer  *closer
+		unwatch func()
+	}
+
+	// ListenerOption configures a listener
+	ListenerOption func(*Listener)
+)
+
+// internal types
+type (
+	// conn is a connection which enforces an idle timeout and untracks itself when closed. The
+	// deadlines set by the owner, e.g. the timeouts of http.Server, are kept, so the idle timeout
+	// only ever shortens them.
+	conn struct {
+		net.Conn
+		sync.Mutex
+		idle  time.Duration
+		conns *connections
+		once  sync.Once
+		read  time.Time
+		write time.Time
+	}
+
+	// drain tracks the drain window of a listener
+	drain struct {
+		sync.Mutex
+		delay     time.Duration
+		unhealthy time.Time
+		started   time.Time
+	}
+
+	// closer closes a listener once
+	closer struct {
+		once sync.Once
+		err  error
+	}
+
+	// connections is a sync-set of the live connections of a listener
+	connections struct {
+		sync.Mutex
+		items map[*conn]struct{}
+	}
+)
+
+// WithIdleTimeout closes connections which have not read or written for the given duration.
+// Deadlines set on the connection, e.g. by the ReadTimeout and WriteTimeout of http.Server, still
+// apply when they come first.
+func WithIdleTimeout(idle time.Duration) ListenerOption {
+	return func(ln *Listener) {
+		ln.idle = idle
+	}
+}
+
+// WithCloseOnUnhealthy closes all live connections as soon as the doctor becomes unhealthy,
+// also when it is forced with SetUnhealthy, instead of only refusing new ones
+func WithCloseOnUnhealthy() ListenerOption {
+	return func(ln *Listener) {
+		ln.conns = &connections{items: make(map[*conn]struct{})}
+	}
+}
+
+// WithDrainDelay keeps accepting connections for the given delay after the listener first sees
+// the doctor unhealthy, or after Drain is called, so the load balancer can notice and stop sending
+// traffic before connections are refused. The window restarts when the doctor recovers in the
+// meantime, unless the drain was triggered explicitly. The delay passes on the clock of the
+// doctor.
+func WithDrainDelay(delay time.Duration) ListenerOption {
+	return func(ln *Listener) {
+		ln.drain.delay = delay
+	}
 }
 
-// NewListener instantiates a new health listener.
-func NewListener(listener net.Listener, health *Doctor) Listener {
-	return Listener{
+// WithStopAccepting makes Accept fail with ErrDrained instead of closing the connections it
+// refuses, once Drain was called and the drain delay has passed. http.Server.Serve returns the
+// error, so the server stops serving; call http.Server.Shutdown afterwards to let the live
+// connections finish. Connections refused merely because the doctor is unhealthy, e.g. at
+// startup or during a blip, are closed as usual and Serve keeps running.
+func WithStopAccepting() ListenerOption {
+	return func(ln *Listener) {
+		ln.stop = true
+	}
+}
+
+// WithAcceptPolicy lets the policy decide whether the doctor is fit for new connections, instead
+// of Healthy, e.g. to shed traffic while degraded as well. WithCloseOnUnhealthy follows it too.
+func WithAcceptPolicy(accept func(*Doctor) bool) ListenerOption {
+	return func(ln *Listener) {
+		ln.accept = accept
+	}
+}
+
+// WithFailOpen accepts connections during the first round of probes, until every registered
+// check has a result, whatever the health of the doctor unless it is forced unhealthy with
+// SetUnhealthy. Checks which were not probed yet fail, so by default the listener fails closed
+// and refuses the traffic which would warm the service up. The first round is over for good
+// once all checks have run; from then on the listener follows the health, or the accept policy.
+func WithFailOpen() ListenerOption {
+	return func(ln *Listener) {
+		ln.open = true
+	}
+}
+
+// NewListener instantiates a new health listener. It fails closed while checks have no result
+// yet, see WithFailOpen.
+func NewListener(listener net.Listener, health *Doctor, opts ...ListenerOption) Listener {
+	ln := Listener{
 		Listener: listener,
 		health:   health,
+		accept:   (*Doctor).Healthy,
+		drain:    &drain{},
+		closer:   &closer{},
+		unwatch:  func() {},
+	}
+	for _, opt := range opts {
+		opt(&ln)
 	}
+	if accept := ln.accept; ln.open {
+		ln.accept = func(health *Doctor) bool {
+			return accept(health) || (health.override.Load() == nil && len(health.booting()) > 0)
+		}
+	}
+	if conns, accept := ln.conns, ln.accept; conns != nil {
+		ln.unwatch = health.OnChange(func() {
+			if !accept(health) {
+				conns.close()
+			}
+		})
+	}
+	return ln
 }
 
-// Accept health aware connections
+// Accept health aware connections. With an idle timeout or WithCloseOnUnhealthy, the connection
+// is wrapped; its NetConn method returns the connection of the underlying listener.
 func (ln Listener) Accept() (c net.Conn, err error) {
 	c, err = ln.Listener.Accept()
 	if err != nil {
@@ -27,10 +158,177 @@ func (ln Listener) Accept() (c net.Conn, err error) {
 
 	// Cleanly close the connection when the service is unhealthy. The server
 	// keeps running though until it recovers.
-	if !ln.health.Healthy() {
+	if now := ln.health.clock.Now(); !ln.drain.accepting(ln.accept(ln.health), now) {
 		c.Close()
+		if ln.stop && ln.drain.drained(now) {
+			return nil, ErrDrained
+		}
+	}
+
+	if ln.idle <= 0 && ln.conns == nil {
+		return c, nil
 	}
 
 	// wrap the connection in a connection which can handle timeouts
-	return c, nil
+	wrapped := &conn{Conn: c, idle: ln.idle, conns: ln.conns}
+	if ln.conns != nil {
+		ln.conns.add(wrapped)
+	}
+	return wrapped, nil
+}
+
+// Drain starts draining the listener: after the drain delay it refuses connections, whatever
+// the health of the doctor
+func (ln Listener) Drain() {
+	ln.drain.Lock()
+	defer ln.drain.Unlock()
+	if ln.drain.started.IsZero() {
+		ln.drain.started = ln.health.clock.Now()
+	}
+}
+
+// Close stops watching the doctor and closes the listener. It is idempotent, so it may be called
+// after http.Server.Shutdown closed the listener already; later calls return the result of the
+// first.
+func (ln Listener) Close() error {
+	ln.closer.once.Do(func() {
+		ln.unwatch()
+		ln.closer.err = ln.Listener.Close()
+	})
+	return ln.closer.err
+}
+
+// Read implements net.Conn, extending the deadline by the idle timeout, up to the read deadline
+// of the owner
+func (c *conn) Read(b []byte) (int, error) {
+	if c.idle > 0 {
+		c.Lock()
+		deadline := c.deadline(c.read)
+		c.Unlock()
+		if err := c.Conn.SetReadDeadline(deadline); err != nil {
+			return 0, err
+		}
+	}
+	return c.Conn.Read(b)
+}
+
+// Write implements net.Conn, extending the deadline by the idle timeout, up to the write
+// deadline of the owner
+func (c *conn) Write(b []byte) (int, error) {
+	if c.idle > 0 {
+		c.Lock()
+		deadline := c.deadline(c.write)
+		c.Unlock()
+		if err := c.Conn.SetWriteDeadline(deadline); err != nil {
+			return 0, err
+		}
+	}
+	return c.Conn.Write(b)
+}
+
+// SetDeadline implements net.Conn, remembering the deadlines of the owner
+func (c *conn) SetDeadline(t time.Time) error {
+	c.Lock()
+	defer c.Unlock()
+	c.read, c.write = t, t
+	return c.Conn.SetDeadline(t)
+}
+
+// SetReadDeadline implements net.Conn, remembering the read deadline of the owner
+func (c *conn) SetReadDeadline(t time.Time) error {
+	c.Lock()
+	defer c.Unlock()
+	c.read = t
+	return c.Conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline implements net.Conn, remembering the write deadline of the owner
+func (c *conn) SetWriteDeadline(t time.Time) error {
+	c.Lock()
+	defer c.Unlock()
+	c.write = t
+	return c.Conn.SetWriteDeadline(t)
+}
+
+// deadline returns the idle deadline, or the deadline of the owner when that comes first. The
+// connection must be locked.
+func (c *conn) deadline(owner time.Time) time.Time {
+	idle := time.Now().Add(c.idle)
+	if !owner.IsZero() && owner.Before(idle) {
+		return owner
+	}
+	return idle
+}
+
+// NetConn returns the underlying connection, e.g. the *tls.Conn when the listener wraps a TLS
+// listener. The HTTP server only recognizes TLS connections by their type, so prefer wrapping
+// the health listener with tls.NewListener over the other way around.
+func (c *conn) NetConn() net.Conn {
+	return c.Conn
+}
+
+// Unwrap returns the underlying connection, like NetConn
+func (c *conn) Unwrap() net.Conn {
+	return c.Conn
+}
+
+// Close implements net.Conn
+func (c *conn) Close() error {
+	c.once.Do(func() {
+		if c.conns != nil {
+			c.conns.remove(c)
+		}
+	})
+	return c.Conn.Close()
+}
+
+// accepting tells whether new connections are accepted
+func (d *drain) accepting(healthy bool, now time.Time) bool {
+	if d.drained(now) {
+		return false
+	}
+	d.Lock()
+	defer d.Unlock()
+	if healthy {
+		d.unhealthy = time.Time{}
+		return true
+	}
+	if d.unhealthy.IsZero() {
+		d.unhealthy = now
+	}
+	return now.Sub(d.unhealthy) < d.delay
+}
+
+// drained tells whether Drain was called and its delay has passed
+func (d *drain) drained(now time.Time) bool {
+	d.Lock()
+	defer d.Unlock()
+	return !d.started.IsZero() && now.Sub(d.started) >= d.delay
+}
+
+// add a connection to the set
+func (cs *connections) add(c *conn) {
+	cs.Lock()
+	defer cs.Unlock()
+	cs.items[c] = struct{}{}
+}
+
+// remove a connection from the set
+func (cs *connections) remove(c *conn) {
+	cs.Lock()
+	defer cs.Unlock()
+	delete(cs.items, c)
+}
+
+// close all connections in the set
+func (cs *connections) close() {
+	cs.Lock()
+	conns := make([]*conn, 0, len(cs.items))
+	for c := range cs.items {
+		conns = append(conns, c)
+	}
+	cs.Unlock()
+	for _, c := range conns {
+		c.Close()
+	}
 }
@@ -0,0 +1,111 @@
+package doctor
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// accept dials the listener and returns the accepted connection
+func accept(t *testing.T, ln net.Listener) net.Conn {
+	t.Helper()
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// listen returns a TCP listener on a free local port
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	return ln
+}
+
+func TestNetConnUnwrapsTCP(t *testing.T) {
+	ln := NewListener(listen(t), NewDoctor(), WithIdleTimeout(time.Minute))
+	c := accept(t, ln)
+	wrapped, ok := c.(interface{ NetConn() net.Conn })
+	if !ok {
+		t.Fatalf("%T has no NetConn", c)
+	}
+	if _, ok := wrapped.NetConn().(*net.TCPConn); !ok {
+		t.Fatalf("NetConn returned %T, want *net.TCPConn", wrapped.NetConn())
+	}
+}
+
+func TestNetConnUnwrapsTLS(t *testing.T) {
+	ln := NewListener(tls.NewListener(listen(t), &tls.Config{}), NewDoctor(), WithCloseOnUnhealthy())
+	c := accept(t, ln)
+	wrapped, ok := c.(interface{ NetConn() net.Conn })
+	if !ok {
+		t.Fatalf("%T has no NetConn", c)
+	}
+	if _, ok := wrapped.NetConn().(*tls.Conn); !ok {
+		t.Fatalf("NetConn returned %T, want *tls.Conn", wrapped.NetConn())
+	}
+}
+
+func TestTLSListenerKeepsTLSConn(t *testing.T) {
+	ln := tls.NewListener(NewListener(listen(t), NewDoctor(), WithIdleTimeout(time.Minute)), &tls.Config{})
+	c, ok := accept(t, ln).(*tls.Conn)
+	if !ok {
+		t.Fatalf("accepted %T, want *tls.Conn", c)
+	}
+	wrapped, ok := c.NetConn().(*conn)
+	if !ok {
+		t.Fatalf("TLS connection wraps %T, want the health connection", c.NetConn())
+	}
+	if _, ok := wrapped.NetConn().(*net.TCPConn); !ok {
+		t.Fatalf("NetConn returned %T, want *net.TCPConn", wrapped.NetConn())
+	}
+}
+
+func TestListenerFollowsDoctor(t *testing.T) {
+	health := NewDoctor()
+	ln := NewListener(listen(t), health)
+
+	// a healthy doctor hands the connection out open
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Write([]byte("ok")); err != nil {
+		t.Fatalf("write on accepted connection: %v", err)
+	}
+	c.Close()
+
+	// an unhealthy one closes it right away
+	health.SetUnhealthy("maintenance")
+	client, err = net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, err := ln.Accept(); err != nil {
+		t.Fatal(err)
+	}
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	var timeout net.Error
+	if _, err := client.Read(make([]byte, 1)); err == nil || errors.As(err, &timeout) && timeout.Timeout() {
+		t.Fatalf("connection left open while unhealthy: %v", err)
+	}
+}
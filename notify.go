@@ -0,0 +1,108 @@
+package doctor
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type (
+	// Notifier is told about the transitions of the checks, e.g. to page someone
+	Notifier interface {
+
+		// OnHealthy is called when the check becomes healthy
+		OnHealthy(ctx context.Context, name string)
+
+		// OnUnhealthy is called when the check becomes unhealthy, with the error of the probe
+		OnUnhealthy(ctx context.Context, name string, err error)
+	}
+
+	// notifiers is a sync-list of the registered notifiers
+	notifiers struct {
+		sync.RWMutex
+		next  int
+		items map[int]*notifier
+	}
+
+	// notifier is a registered notifier with its timeout and the transitions it still has to be
+	// told about, in order. A worker delivers them while there are any.
+	notifier struct {
+		Notifier
+		timeout time.Duration
+		sync.Mutex
+		queue   []transition
+		running bool
+	}
+
+	// transition is a change of the state of a check, for the notifiers
+	transition struct {
+		name    string
+		healthy bool
+		err     error
+	}
+)
+
+// Notify registers a notifier. It is called on a goroutine of its own, one transition after
+// another in the order they happened, each with a context which is done after the timeout,
+// DefaultTimeout when zero, so a slow notifier cannot stall the probes. The returned function
+// unregisters it; transitions which are queued already are still delivered.
+func (health *Doctor) Notify(n Notifier, timeout time.Duration) func() {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	health.notifiers.Lock()
+	defer health.notifiers.Unlock()
+	id := health.notifiers.next
+	health.notifiers.next++
+	health.notifiers.items[id] = &notifier{Notifier: n, timeout: timeout}
+	return func() {
+		health.notifiers.Lock()
+		defer health.notifiers.Unlock()
+		delete(health.notifiers.items, id)
+	}
+}
+
+// notify all the notifiers about a transition, without waiting for them
+func (ns *notifiers) notify(name string, healthy bool, err error) {
+	ns.RLock()
+	defer ns.RUnlock()
+	for _, n := range ns.items {
+		n.enqueue(transition{name: name, healthy: healthy, err: err})
+	}
+}
+
+// enqueue a transition, starting the worker unless it runs already
+func (n *notifier) enqueue(t transition) {
+	n.Lock()
+	defer n.Unlock()
+	n.queue = append(n.queue, t)
+	if !n.running {
+		n.running = true
+		go n.deliver()
+	}
+}
+
+// deliver the queued transitions one by one, until there are none left
+func (n *notifier) deliver() {
+	for {
+		n.Lock()
+		if len(n.queue) == 0 {
+			n.running = false
+			n.Unlock()
+			return
+		}
+		t := n.queue[0]
+		n.queue = n.queue[1:]
+		n.Unlock()
+
+		func() {
+			ctx, cancel := context.WithTimeout(context.Background(), n.timeout)
+			defer cancel()
+			if t.healthy {
+				n.OnHealthy(ctx, t.name)
+			} else {
+				n.OnUnhealthy(ctx, t.name, t.err)
+			}
+		}()
+	}
+}
@@ -0,0 +1,90 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is a notifier which records the transitions, the first one held back until released
+type recorder struct {
+	sync.Mutex
+	release chan struct{}
+	seen    []string
+	done    chan struct{}
+}
+
+func (r *recorder) OnHealthy(ctx context.Context, name string) {
+	r.record(name + " up")
+}
+
+func (r *recorder) OnUnhealthy(ctx context.Context, name string, err error) {
+	r.record(name + " down")
+}
+
+func (r *recorder) record(transition string) {
+	r.Lock()
+	first := len(r.seen) == 0
+	r.seen = append(r.seen, transition)
+	r.Unlock()
+	if first {
+		<-r.release
+	}
+	r.done <- struct{}{}
+}
+
+func TestNotifierKeepsOrder(t *testing.T) {
+	health := NewDoctor()
+	t.Cleanup(health.Stop)
+	r := &recorder{release: make(chan struct{}), done: make(chan struct{}, 2)}
+	health.Notify(r, time.Second)
+
+	// a slow delivery of the failure must not let the recovery overtake it
+	health.notifiers.notify("db", false, errors.New("down"))
+	health.notifiers.notify("db", true, nil)
+	close(r.release)
+	<-r.done
+	<-r.done
+
+	r.Lock()
+	defer r.Unlock()
+	if len(r.seen) != 2 || r.seen[0] != "db down" || r.seen[1] != "db up" {
+		t.Fatalf("delivered %v, want db down then db up", r.seen)
+	}
+}
+
+func TestNotifierTimesOutEachDelivery(t *testing.T) {
+	health := NewDoctor()
+	t.Cleanup(health.Stop)
+	errs := make(chan error, 2)
+	health.Notify(notifierFunc(func(ctx context.Context) {
+		<-ctx.Done()
+		errs <- ctx.Err()
+	}), 10*time.Millisecond)
+
+	health.notifiers.notify("db", false, errors.New("down"))
+	health.notifiers.notify("db", true, nil)
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("delivery %d: %v", i, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("delivery %d not bound by the timeout", i)
+		}
+	}
+}
+
+// notifierFunc is a notifier which calls the function on every transition
+type notifierFunc func(ctx context.Context)
+
+func (fn notifierFunc) OnHealthy(ctx context.Context, name string) {
+	fn(ctx)
+}
+
+func (fn notifierFunc) OnUnhealthy(ctx context.Context, name string, err error) {
+	fn(ctx)
+}
@@ -0,0 +1,176 @@
+package doctor
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Option configures a doctor
+type Option func(*Doctor)
+
+// WithDefaultInterval sets the interval of checks which leave it zero
+func WithDefaultInterval(interval time.Duration) Option {
+	return func(health *Doctor) {
+		health.interval = interval
+	}
+}
+
+// WithDefaultTimeout sets the timeout of checks which leave it zero
+func WithDefaultTimeout(timeout time.Duration) Option {
+	return func(health *Doctor) {
+		health.timeout = timeout
+	}
+}
+
+// WithMaxChecks limits the number of checks which can be registered. Zero means unlimited.
+func WithMaxChecks(max int) Option {
+	return func(health *Doctor) {
+		health.maxChecks = max
+	}
+}
+
+// WithLatencyWindow keeps the durations of the last size probes of every check, to report their
+// percentiles in Status and the verbose output. Zero, the default, keeps none.
+func WithLatencyWindow(size int) Option {
+	return func(health *Doctor) {
+		health.window = size
+	}
+}
+
+// WithHistory keeps the last size probe results of every check, see History. Zero, the default,
+// keeps none.
+func WithHistory(size int) Option {
+	return func(health *Doctor) {
+		health.results = size
+	}
+}
+
+// WithStaleness starts a watchdog which marks a probed check as failing when it was not probed
+// within the multiple of its interval, e.g. 3, because its probe loop is wedged. Backoff, jitter
+// and the initial delay are taken into account. Zero, the default, disables the watchdog.
+func WithStaleness(multiplier float64) Option {
+	return func(health *Doctor) {
+		health.staleness = multiplier
+	}
+}
+
+// WithMaxConcurrentProbes limits the number of scheduled probes which run at once, the others
+// queue until a probe finishes. Zero means unlimited. Probes forced with RunCheck are not limited.
+func WithMaxConcurrentProbes(max int) Option {
+	return func(health *Doctor) {
+		health.probes = nil
+		if max > 0 {
+			health.probes = make(chan struct{}, max)
+		}
+	}
+}
+
+// WithLogger sets the logger of the doctor. Nothing is logged without one.
+func WithLogger(logger *slog.Logger) Option {
+	return func(health *Doctor) {
+		health.logger = logger
+	}
+}
+
+// WithContext sets the root context of the doctor. All checks stop when it is done.
+func WithContext(ctx context.Context) Option {
+	return func(health *Doctor) {
+		health.ctx = ctx
+	}
+}
+
+// WithHealthPolicy lets the policy decide whether the service is healthy, instead of requiring
+// all critical checks to pass. It gets the state of all checks, sorted by name.
+func WithHealthPolicy(policy func(statuses []CheckStatus) bool) Option {
+	return func(health *Doctor) {
+		health.policy = policy
+	}
+}
+
+// WithStartingState reports the service as starting instead of down while checks wait for their
+// first result, and leaves those checks out of the errors. The service is still not healthy, so
+// the status code stays the unhealthy one.
+func WithStartingState() Option {
+	return func(health *Doctor) {
+		health.startup = true
+	}
+}
+
+// WithoutScheduler registers probed checks without starting their probe loops, e.g. for a
+// healthcheck command which only calls ProbeOnce. The checks are probed on demand only, by
+// RunCheck, ProbeHandler and ProbeOnce, so nothing competes with those probes. Push checks,
+// checks driven by Results and embedded doctors report as usual. The watchdog of WithStaleness
+// is not started, the checks are not expected to be probed regularly.
+func WithoutScheduler() Option {
+	return func(health *Doctor) {
+		health.manual = true
+	}
+}
+
+// WithSyncFirstProbe runs the first probe of a check within Investigate, so its state is known
+// once the check is registered. The probe is bounded by the timeout of the check. Dependencies
+// are probed before their dependents. Checks with an initial delay, push checks and embedded
+// doctors start as usual.
+func WithSyncFirstProbe() Option {
+	return func(health *Doctor) {
+		health.sync = true
+	}
+}
+
+// WithMetadata attaches static metadata to the doctor, e.g. the version and the commit of the
+// build. It is shown in the verbose output.
+func WithMetadata(metadata map[string]string) Option {
+	return func(health *Doctor) {
+		health.metadata = metadata
+	}
+}
+
+// WithStartTime sets the start time of the process, from which the uptime in the verbose output
+// is derived. The creation of the doctor by default.
+func WithStartTime(started time.Time) Option {
+	return func(health *Doctor) {
+		health.started = started
+	}
+}
+
+// WithChangeHeader sets the header X-Health-Changed on the responses of Handler, true when the
+// state of the checks changed since the previous response, e.g. to trigger a scrape right away
+func WithChangeHeader() Option {
+	return func(health *Doctor) {
+		health.served = &served{}
+	}
+}
+
+// WithRenderer replaces the JSON body of Handler with the JSON encoding of whatever the renderer
+// returns for a snapshot, e.g. to match an existing health schema. The status code still
+// follows the health of the service.
+func WithRenderer(renderer func(Snapshot) any) Option {
+	return func(health *Doctor) {
+		health.renderer = renderer
+	}
+}
+
+// WithHealthyStatus sets the status code of the handlers while the service is up or degraded,
+// 200 by default
+func WithHealthyStatus(code int) Option {
+	return func(health *Doctor) {
+		health.healthyStatus = code
+	}
+}
+
+// WithUnhealthyStatus sets the status code of the handlers while the service is down, 503 by
+// default
+func WithUnhealthyStatus(code int) Option {
+	return func(health *Doctor) {
+		health.unhealthyStatus = code
+	}
+}
+
+// WithClock sets the clock which schedules the probes and stamps their results, the wall clock
+// by default
+func WithClock(clock Clock) Option {
+	return func(health *Doctor) {
+		health.clock = clock
+	}
+}
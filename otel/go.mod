@@ -0,0 +1,9 @@
+module github.com/decoomanj/doctor/otel
+
+go 1.21.1
+
+require (
+	github.com/decoomanj/doctor v0.0.0-20261014183040-f426c7cb9bd6
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
+)
@@ -0,0 +1,41 @@
+package otel
+
+import (
+	"context"
+
+	"github.com/decoomanj/doctor"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Instrument returns a copy of the check whose handler runs every probe in a span of the given
+// tracer. The span is named after the check, carries the probe ID and records its error; a
+// Warning or a CheckError without error keeps the span ok, as it keeps the check healthy.
+// A check without handler, e.g. a push check, is returned as it is. Register the returned
+// check with the doctor.
+func Instrument(tracer trace.Tracer, check *doctor.Check) *doctor.Check {
+	if check.Handler == nil {
+		return check
+	}
+	handler := check.Handler
+	name := check.Name
+	instrumented := *check
+	instrumented.Handler = func(ctx context.Context) error {
+		ctx, span := tracer.Start(ctx, "health-check "+name, trace.WithAttributes(
+			attribute.String("doctor.check", name),
+			attribute.String("doctor.probe", doctor.ProbeID(ctx)),
+		))
+		defer span.End()
+
+		err := handler(ctx)
+		if doctor.Failed(err) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		} else {
+			span.SetStatus(codes.Ok, "")
+		}
+		return err
+	}
+	return &instrumented
+}
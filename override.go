@@ -0,0 +1,16 @@
+package doctor
+
+// SetUnhealthy forces the service unhealthy whatever the state of the checks, e.g. to drain it
+// on shutdown. Healthy, Handler, ReadinessHandler and Listener follow it and the status page shows
+// the reason; liveness is not affected. The listeners of OnChange are notified. It lasts until
+// ClearOverride.
+func (health *Doctor) SetUnhealthy(reason string) {
+	health.override.Store(&reason)
+	health.aggregate()
+}
+
+// ClearOverride ends SetUnhealthy, the checks decide again
+func (health *Doctor) ClearOverride() {
+	health.override.Store(nil)
+	health.aggregate()
+}
@@ -0,0 +1,54 @@
+package doctor_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/decoomanj/doctor"
+	"github.com/decoomanj/doctor/doctortest"
+)
+
+func TestAcceptNegotiatesPlainText(t *testing.T) {
+	d := doctortest.New()
+	defer d.Stop()
+	for _, c := range []struct {
+		accept []string
+		plain  bool
+	}{
+		{nil, false},
+		{[]string{"text/plain"}, true},
+		{[]string{"text/*"}, true},
+		{[]string{"*/*"}, false},
+		{[]string{"application/json"}, false},
+		// the first known media type wins, quality values are ignored
+		{[]string{"image/png, text/plain;q=0.1, application/json"}, true},
+		{[]string{"application/json;q=0.1, text/plain"}, false},
+		{[]string{"image/png", "text/plain"}, true},
+		{[]string{"image/png"}, false},
+	} {
+		var header []string
+		for _, accept := range c.accept {
+			header = append(header, "Accept", accept)
+		}
+		for _, up := range []bool{true, false} {
+			if up {
+				d.ClearOverride()
+			} else {
+				d.SetUnhealthy("maintenance")
+			}
+			w := get(d, http.MethodGet, doctor.PathHealth, header...)
+			contentType, body := w.Header().Get("Content-Type"), w.Body.String()
+			switch {
+			case c.plain && up && (body != "OK\n" || w.Code != http.StatusOK):
+				t.Fatalf("Accept %q, up: status %d, body %q", c.accept, w.Code, body)
+			case c.plain && !up && (body != "UNAVAILABLE\n" || w.Code != http.StatusServiceUnavailable):
+				t.Fatalf("Accept %q, down: status %d, body %q", c.accept, w.Code, body)
+			case c.plain != strings.HasPrefix(contentType, "text/plain"):
+				t.Fatalf("Accept %q: Content-Type %q, want plain text %t", c.accept, contentType, c.plain)
+			case !c.plain && !strings.HasPrefix(body, "{"):
+				t.Fatalf("Accept %q: body %q, want JSON", c.accept, body)
+			}
+		}
+	}
+}
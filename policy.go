@@ -0,0 +1,34 @@
+package doctor
+
+import "sync"
+
+// PercentUnhealthy returns a health policy for WithHealthPolicy, which takes the service down
+// when more than the down fraction of the checks fails, e.g. 0.3, and only brings it up again
+// when less than the up fraction fails, e.g. 0.2. The gap between both keeps the service from
+// flapping at the boundary. Starting, disabled and skipped checks do not count as failing.
+func PercentUnhealthy(down, up float64) func([]CheckStatus) bool {
+	var mu sync.Mutex
+	healthy := true
+	return func(statuses []CheckStatus) bool {
+		failing := 0
+		for _, status := range statuses {
+			if !status.Healthy && !status.Starting && !status.Disabled && !status.Skipped {
+				failing++
+			}
+		}
+		var fraction float64
+		if len(statuses) > 0 {
+			fraction = float64(failing) / float64(len(statuses))
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case healthy && fraction > down:
+			healthy = false
+		case !healthy && fraction < up:
+			healthy = true
+		}
+		return healthy
+	}
+}
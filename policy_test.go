@@ -0,0 +1,50 @@
+package doctor
+
+import "testing"
+
+// statuses returns ten statuses, the given number of them failing
+func statuses(failing int) []CheckStatus {
+	statuses := make([]CheckStatus, 10)
+	for i := range statuses {
+		statuses[i].Healthy = i >= failing
+	}
+	return statuses
+}
+
+func TestPercentUnhealthyHysteresis(t *testing.T) {
+	policy := PercentUnhealthy(0.3, 0.2)
+	for _, step := range []struct {
+		failing int
+		healthy bool
+	}{
+		{0, true},
+		{3, true},
+		// beyond the down fraction
+		{4, false},
+		// between both fractions the state is kept
+		{3, false},
+		{2, false},
+		// below the up fraction
+		{1, true},
+		{2, true},
+		{3, true},
+		{10, false},
+		{0, true},
+	} {
+		if healthy := policy(statuses(step.failing)); healthy != step.healthy {
+			t.Fatalf("%d of 10 failing: healthy %t, want %t", step.failing, healthy, step.healthy)
+		}
+	}
+}
+
+func TestPercentUnhealthyIgnoresInactiveChecks(t *testing.T) {
+	policy := PercentUnhealthy(0.3, 0.2)
+	checks := statuses(5)
+	checks[0].Starting, checks[1].Disabled, checks[2].Skipped = true, true, true
+	if !policy(checks) {
+		t.Fatal("starting, disabled and skipped checks counted as failing")
+	}
+	if !PercentUnhealthy(0.3, 0.2)(nil) {
+		t.Fatal("unhealthy without checks")
+	}
+}
@@ -0,0 +1,36 @@
+package doctor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// probeKey is the context key of the probe ID
+type probeKey struct{}
+
+// RequestIDHeader is the request header whose value ProbeHandler takes as probe ID
+const RequestIDHeader = "X-Request-Id"
+
+// WithProbeID returns a copy of the context which carries the probe ID, e.g. the correlation ID
+// of a request which forces probes with RunCheck
+func WithProbeID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, probeKey{}, id)
+}
+
+// ProbeID returns the ID of the probe from the context of a handler, e.g. to log or trace it.
+// Every probe has one: the given one for forced probes, a generated one otherwise.
+func ProbeID(ctx context.Context) string {
+	id, _ := ctx.Value(probeKey{}).(string)
+	return id
+}
+
+// identify returns the context with a generated probe ID, unless it carries one already
+func identify(ctx context.Context) context.Context {
+	if ProbeID(ctx) != "" {
+		return ctx
+	}
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+	return WithProbeID(ctx, hex.EncodeToString(id[:]))
+}
@@ -0,0 +1,92 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// logs is a buffer of JSON log lines which is safe for concurrent writes
+type logs struct {
+	sync.Mutex
+	bytes.Buffer
+}
+
+func (l *logs) Write(p []byte) (int, error) {
+	l.Lock()
+	defer l.Unlock()
+	return l.Buffer.Write(p)
+}
+
+// find returns the probe attribute of the first line with the message, whether one was found
+func (l *logs) find(t *testing.T, msg string) (string, bool) {
+	t.Helper()
+	l.Lock()
+	defer l.Unlock()
+	for _, line := range bytes.Split(l.Bytes(), []byte("\n")) {
+		var record map[string]any
+		if len(line) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatal(err)
+		}
+		if record["msg"] == msg {
+			probe, _ := record["probe"].(string)
+			return probe, true
+		}
+	}
+	return "", false
+}
+
+func TestTransitionLogsCarryProbeID(t *testing.T) {
+	out := &logs{}
+	health := NewDoctor(WithoutScheduler(), WithLogger(slog.New(slog.NewJSONHandler(out, nil))))
+	t.Cleanup(health.Stop)
+	down := true
+	err := health.Investigate(&Check{
+		Name:     "db",
+		Interval: time.Hour,
+		Timeout:  10 * time.Millisecond,
+		Handler: func(ctx context.Context) error {
+			switch ProbeID(ctx) {
+			case "slow":
+				<-ctx.Done()
+				return ctx.Err()
+			case "recover":
+				return nil
+			}
+			if down {
+				return errors.New("down")
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	health.RunCheck(WithProbeID(ctx, "fail"), "db")
+	health.RunCheck(WithProbeID(ctx, "recover"), "db")
+	for i := 0; i < timeoutHint; i++ {
+		health.RunCheck(WithProbeID(ctx, "slow"), "db")
+		// wait for the abandoned handler to leave the probing slot
+		health.checks.items["db"].probing <- struct{}{}
+		<-health.checks.items["db"].probing
+	}
+	for msg, want := range map[string]string{
+		"health-check failing":   "fail",
+		"health-check recovered": "recover",
+		"health-check keeps timing out, its timeout may be too short": "slow",
+	} {
+		if probe, ok := out.find(t, msg); !ok || probe != want {
+			t.Fatalf("%q logged %t with probe %q, want %q", msg, ok, probe, want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	// Report is the result of ProbeOnce
+	Report struct {
+		// Whether no critical check failed and every group kept its quorum
+		Healthy bool
+
+		// The results of the probed checks, sorted by name
+		Checks []ReportCheck
+	}
+
+	// ReportCheck is the result of a single check in a Report
+	ReportCheck struct {
+		// The name of the check
+		Name string
+
+		// The severity of the check
+		Severity Severity
+
+		// The failure of the probe, nil when it succeeded
+		Err error
+
+		// The duration of the probe
+		Duration time.Duration
+	}
+)
+
+// ProbeOnce probes every enabled check once, concurrently, and reports the results, e.g. for a
+// healthcheck command which exits non-zero when unhealthy. Each probe is bound by the timeout
+// of its check and waits for a probe in flight first. The results are not recorded: the state
+// of the checks, their counters and the override are left as they are. Push checks are left
+// out, they cannot be probed; as members of a group they count with their last known state.
+// Groups are evaluated by their quorum, as in Healthy. The health policy is not consulted, since
+// it may keep state across calls, as PercentUnhealthy does, which one-off results would disturb.
+// Create the doctor WithoutScheduler to register the checks without starting their probe loops.
+func (health *Doctor) ProbeOnce(ctx context.Context) Report {
+	health.checks.RLock()
+	checks := make([]*healthCheckStatus, 0, len(health.checks.items))
+	quorum := make(map[*healthGroup]int)
+	tally := func(group *healthGroup, healthy bool) {
+		if healthy {
+			quorum[group]++
+		} else if _, ok := quorum[group]; !ok {
+			quorum[group] = 0
+		}
+	}
+	for _, hc := range health.checks.items {
+		hc.RLock()
+		if hc.Handler != nil && !hc.disabled {
+			checks = append(checks, hc)
+		} else if hc.group != nil {
+			tally(hc.group, !hc.failing())
+		}
+		hc.RUnlock()
+	}
+	health.checks.RUnlock()
+
+	report := Report{Healthy: true, Checks: make([]ReportCheck, len(checks))}
+	var wg sync.WaitGroup
+	for i, hc := range checks {
+		wg.Add(1)
+		go func(i int, hc *healthCheckStatus) {
+			defer wg.Done()
+			begin := health.clock.Now()
+			err := hc.once(ctx, health)
+			report.Checks[i] = ReportCheck{Name: hc.Name, Severity: hc.Severity, Err: err, Duration: health.clock.Now().Sub(begin)}
+		}(i, hc)
+	}
+	wg.Wait()
+
+	for i, hc := range checks {
+		failed := report.Checks[i].Err != nil
+		switch {
+		case hc.group != nil:
+			// the severity of a member is ignored, its group decides
+			tally(hc.group, !failed)
+		case failed && hc.Severity == Critical:
+			report.Healthy = false
+		}
+	}
+	for group, n := range quorum {
+		if n < group.min {
+			report.Healthy = false
+		}
+	}
+	sort.Slice(report.Checks, func(i, j int) bool {
+		return report.Checks[i].Name < report.Checks[j].Name
+	})
+	return report
+}
+
+// once probes the check like the probe loop does, but returns the failure instead of recording
+// it. A handler which ignores its context is left behind once the timeout is over.
+func (hc *healthCheckStatus) once(ctx context.Context, health *Doctor) error {
+	select {
+	case hc.probing <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	parent := identify(ctx)
+	if hc.ContextFunc != nil {
+		parent = hc.ContextFunc(parent)
+	}
+	subctx, cancel := health.clock.WithTimeout(parent, hc.timeout())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		defer func() { <-hc.probing }()
+		_, err, _ := hc.examine(subctx, health, health.clock.Now())
+		done <- failure(err)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-subctx.Done():
+		select {
+		case err := <-done:
+			return err
+		default:
+		}
+		if errors.Is(subctx.Err(), context.DeadlineExceeded) {
+			return &timeoutError{timeout: hc.timeout(), err: subctx.Err()}
+		}
+		return subctx.Err()
+	}
+}
@@ -0,0 +1,99 @@
+package doctor_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/decoomanj/doctor"
+	"github.com/decoomanj/doctor/doctortest"
+)
+
+// replicas registers a group of replicas which needs two healthy ones, the first one down
+func replicas(t *testing.T, d *doctortest.Doctor) {
+	t.Helper()
+	if err := d.Group("replicas", 2); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		down := i == 0
+		err := d.Investigate(&doctor.Check{
+			Name:     fmt.Sprintf("replica-%d", i),
+			Group:    "replicas",
+			Interval: 10 * time.Second,
+			Timeout:  time.Second,
+			Handler: func(context.Context) error {
+				if down {
+					return errors.New("down")
+				}
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestProbeOnceKeepsGroupQuorum(t *testing.T) {
+	d := doctortest.New()
+	defer d.Stop()
+	replicas(t, d)
+	d.Tick(context.Background(), 0)
+	if !d.Healthy() {
+		t.Fatal("group with quorum reported unhealthy")
+	}
+	if report := d.ProbeOnce(context.Background()); !report.Healthy {
+		t.Fatalf("report unhealthy for a group with quorum: %+v", report.Checks)
+	}
+
+	if err := d.Disable("replica-1"); err != nil {
+		t.Fatal(err)
+	}
+	if report := d.ProbeOnce(context.Background()); !report.Healthy {
+		t.Fatal("a disabled member does not count as healthy")
+	}
+	if err := d.Remove("replica-1"); err != nil {
+		t.Fatal(err)
+	}
+	if report := d.ProbeOnce(context.Background()); report.Healthy {
+		t.Fatal("report healthy for a group without quorum")
+	}
+}
+
+func TestProbeOnceLeavesPolicyAlone(t *testing.T) {
+	d := doctortest.New(doctor.WithHealthPolicy(doctor.PercentUnhealthy(0.5, 0.2)))
+	defer d.Stop()
+	failing := false
+	for _, name := range []string{"a", "b"} {
+		err := d.Investigate(&doctor.Check{
+			Name:     name,
+			Interval: 10 * time.Second,
+			Timeout:  time.Second,
+			Handler: func(context.Context) error {
+				if failing {
+					return errors.New("down")
+				}
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.Tick(context.Background(), 0)
+	if !d.Healthy() {
+		t.Fatal("healthy checks reported unhealthy")
+	}
+
+	// a one-off round of failures must not trip the hysteresis of the live state
+	failing = true
+	if report := d.ProbeOnce(context.Background()); report.Healthy {
+		t.Fatal("report healthy with every critical check failing")
+	}
+	if !d.Healthy() {
+		t.Fatal("ProbeOnce changed the live state of the policy")
+	}
+}
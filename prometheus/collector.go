@@ -0,0 +1,100 @@
+package prometheus
+
+import (
+	"github.com/decoomanj/doctor"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector exports the health-checks of a doctor as prometheus metrics:
+//
+//	doctor_check_healthy{name="..."}           1 when the check is healthy, 0 otherwise
+//	doctor_check_duration_seconds{name="..."}  histogram of the probe durations
+//	doctor_check_probes_total{name="..."}      the number of probes
+//	doctor_check_errors_total{name="..."}      the number of probes failed with an error
+//	doctor_check_timeouts_total{name="..."}    the number of probes which timed out
+//
+// All metrics have one series per check name, so the label cardinality grows with the number
+// of registered checks. Do not generate check names dynamically.
+type Collector struct {
+	health   *doctor.Doctor
+	healthy  *prom.Desc
+	probes   *prom.Desc
+	errors   *prom.Desc
+	timeouts *prom.Desc
+	duration *prom.HistogramVec
+}
+
+// NewCollector creates a collector for the given doctor
+func NewCollector(health *doctor.Doctor) *Collector {
+	return &Collector{
+		health: health,
+		healthy: prom.NewDesc(
+			"doctor_check_healthy",
+			"Whether the health-check is healthy (1) or not (0).",
+			[]string{"name"}, nil,
+		),
+		probes: prom.NewDesc(
+			"doctor_check_probes_total",
+			"The number of health-check probes.",
+			[]string{"name"}, nil,
+		),
+		errors: prom.NewDesc(
+			"doctor_check_errors_total",
+			"The number of health-check probes which failed with an error.",
+			[]string{"name"}, nil,
+		),
+		timeouts: prom.NewDesc(
+			"doctor_check_timeouts_total",
+			"The number of health-check probes which timed out.",
+			[]string{"name"}, nil,
+		),
+		duration: prom.NewHistogramVec(prom.HistogramOpts{
+			Name: "doctor_check_duration_seconds",
+			Help: "The duration of the health-check probes.",
+		}, []string{"name"}),
+	}
+}
+
+// Instrument returns a copy of the check which observes the duration of every probe, with its
+// retries, once. It chains into the ContextAspect of the check. A check without handler, e.g. a
+// push check, is returned as it is. Register the returned check with the doctor.
+func (c *Collector) Instrument(check *doctor.Check) *doctor.Check {
+	if check.Handler == nil {
+		return check
+	}
+	aspect := check.ContextAspect
+	duration := c.duration.WithLabelValues(check.Name)
+	instrumented := *check
+	instrumented.ContextAspect = func(probe doctor.AspectContext) error {
+		duration.Observe(probe.Duration.Seconds())
+		if aspect != nil {
+			return aspect(probe)
+		}
+		return probe.Err
+	}
+	return &instrumented
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prom.Desc) {
+	ch <- c.healthy
+	ch <- c.probes
+	ch <- c.errors
+	ch <- c.timeouts
+	c.duration.Describe(ch)
+}
+
+// Collect implements prometheus.Collector. The health state is read live from the doctor.
+func (c *Collector) Collect(ch chan<- prom.Metric) {
+	for _, status := range c.health.Status() {
+		var value float64
+		if status.Healthy {
+			value = 1
+		}
+		ch <- prom.MustNewConstMetric(c.healthy, prom.GaugeValue, value, status.Name)
+		ch <- prom.MustNewConstMetric(c.probes, prom.CounterValue, float64(status.Probes), status.Name)
+		ch <- prom.MustNewConstMetric(c.errors, prom.CounterValue, float64(status.Errors), status.Name)
+		ch <- prom.MustNewConstMetric(c.timeouts, prom.CounterValue, float64(status.Timeouts), status.Name)
+	}
+	c.duration.Collect(ch)
+}
@@ -0,0 +1,39 @@
+package doctor
+
+import "fmt"
+
+// Heartbeat reports a push check as healthy. It turns unhealthy when no heartbeat arrives within
+// its TTL.
+func (health *Doctor) Heartbeat(name string) error {
+	health.checks.RLock()
+	check, ok := health.checks.items[name]
+	health.checks.RUnlock()
+	if !ok {
+		return fmt.Errorf("health-check %q not found", name)
+	}
+	if check.Handler != nil || check.Results != nil {
+		return fmt.Errorf("health-check %q is not a push check", name)
+	}
+
+	check.apply(health, check.next(), nil, health.clock.Now(), 0, "")
+	select {
+	case check.beats <- struct{}{}:
+	default:
+		// the loop is about to restart the TTL anyway
+	}
+	return nil
+}
+
+// expire marks the push check unhealthy whenever its TTL passes without a heartbeat
+func (hc *healthCheckStatus) expire(health *Doctor) {
+	for {
+		select {
+		case <-hc.ctx.Done():
+			return
+		case <-hc.beats:
+			continue
+		case <-health.clock.After(hc.TTL):
+			hc.apply(health, hc.next(), fmt.Errorf("no heartbeat within %s", hc.TTL), health.clock.Now(), 0, "")
+		}
+	}
+}
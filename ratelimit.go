@@ -0,0 +1,65 @@
+package doctor
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// limiter is a token bucket shared by all clients, see WithRateLimit
+type limiter struct {
+	sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// WithRateLimit limits the expensive requests to rate per second, with bursts of up to burst
+// requests: the verbose ones of Handler and the ones of ProbeHandler. The limit is global rather
+// than per client. Requests beyond it get 429 with Retry-After. The terse status page is always
+// served, also with a renderer, so load balancers are not cut off. A burst below 1 is raised to
+// 1, otherwise no request would ever pass.
+func WithRateLimit(rate float64, burst int) Option {
+	if burst < 1 {
+		burst = 1
+	}
+	return func(health *Doctor) {
+		health.limiter = &limiter{rate: rate, burst: float64(burst), tokens: float64(burst)}
+	}
+}
+
+// limit takes a token for the request, or rejects it with 429. It returns true when rejected.
+func (health *Doctor) limit(w http.ResponseWriter) bool {
+	if health.limiter == nil {
+		return false
+	}
+	wait, ok := health.limiter.take(health.clock.Now())
+	if ok {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+	return true
+}
+
+// take a token, refilled at the rate since the last call. Without one it returns how long until
+// the next one.
+func (l *limiter) take(now time.Time) (time.Duration, bool) {
+	l.Lock()
+	defer l.Unlock()
+	if !l.last.IsZero() {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0, true
+	}
+	if l.rate <= 0 {
+		return time.Second, false
+	}
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second)), false
+}
@@ -0,0 +1,82 @@
+package doctor
+
+import (
+	"context"
+	"sort"
+)
+
+// WaitReady blocks until every registered check has succeeded at least once, e.g. to hold back
+// traffic until caches are warm. Disabled checks count as ready, removed ones are not waited
+// for anymore. When the context is done first, a *NotReadyError with the checks which never
+// succeeded is returned.
+func (health *Doctor) WaitReady(ctx context.Context) error {
+	changes := make(chan struct{}, 1)
+	unwatch := health.OnChange(func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	})
+	defer unwatch()
+
+	for {
+		pending := health.checks.pending()
+		if len(pending) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return &NotReadyError{Checks: pending, Err: ctx.Err()}
+		case <-changes:
+		}
+	}
+}
+
+// pending returns the names of the checks which never succeeded, sorted
+func (checks *healthChecks) pending() []string {
+	checks.RLock()
+	defer checks.RUnlock()
+	var pending []string
+	for name, hc := range checks.items {
+		hc.RLock()
+		if hc.lastSuccess.IsZero() && !hc.disabled {
+			pending = append(pending, name)
+		}
+		hc.RUnlock()
+	}
+	sort.Strings(pending)
+	return pending
+}
+
+// booting returns the names of the checks which have no result yet during the first round of
+// probes, sorted. The first round is over for good once every registered check has run, also
+// for checks which are registered later.
+func (health *Doctor) booting() []string {
+	if health.booted.Load() {
+		return nil
+	}
+	unprobed, registered := health.checks.unprobed()
+	if len(unprobed) == 0 && registered > 0 {
+		health.booted.Store(true)
+	}
+	return unprobed
+}
+
+// unprobed returns the names of the checks which have no result yet, sorted, and the number of
+// registered checks. Disabled checks and checks skipped for an unhealthy dependency count as
+// probed.
+func (checks *healthChecks) unprobed() ([]string, int) {
+	checks.RLock()
+	defer checks.RUnlock()
+	var unprobed []string
+	for name, hc := range checks.items {
+		hc.RLock()
+		if hc.lastRun.IsZero() && !hc.disabled && !hc.skipped {
+			unprobed = append(unprobed, name)
+		}
+		hc.RUnlock()
+	}
+	sort.Strings(unprobed)
+	return unprobed, len(checks.items)
+}
@@ -0,0 +1,43 @@
+package doctor
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWaitReadyWakesOnFirstSuccessBelowThreshold(t *testing.T) {
+	health := NewDoctor(WithoutScheduler())
+	t.Cleanup(health.Stop)
+	err := health.Investigate(&Check{
+		Name:             "db",
+		Interval:         time.Hour,
+		SuccessThreshold: 2,
+		Handler: func(context.Context) error {
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	ready := make(chan error, 1)
+	go func() {
+		ready <- health.WaitReady(ctx)
+	}()
+	for waiting := false; !waiting; {
+		// probe only once WaitReady waits for a change
+		health.changes.RLock()
+		waiting = len(health.changes.items) > 0
+		health.changes.RUnlock()
+	}
+	health.Step(context.Background())
+	if err := <-ready; err != nil {
+		t.Fatalf("not ready after the first success: %v", err)
+	}
+	if health.Healthy() {
+		t.Fatal("healthy below the success threshold")
+	}
+}
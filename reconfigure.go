@@ -0,0 +1,47 @@
+package doctor
+
+import (
+	"fmt"
+	"time"
+)
+
+// Reconfigure changes the interval and the timeout of a probed check at runtime, e.g. to probe
+// more often during an incident. Zero values take the defaults, as with Investigate. The running
+// wait for the next probe restarts with the new interval right away.
+func (health *Doctor) Reconfigure(name string, interval, timeout time.Duration) error {
+	health.checks.RLock()
+	check, ok := health.checks.items[name]
+	health.checks.RUnlock()
+	if !ok {
+		return fmt.Errorf("health-check %q not found", name)
+	}
+	if check.Handler == nil || check.follow != nil {
+		return fmt.Errorf("health-check %q is not probed", name)
+	}
+	if interval == 0 {
+		interval = health.interval
+	}
+	if timeout == 0 {
+		timeout = health.timeout
+	}
+	if interval <= 0 || timeout <= 0 {
+		return fmt.Errorf("health-check %q: interval %s and timeout %s must be positive", name, interval, timeout)
+	}
+	if timeout >= interval {
+		return fmt.Errorf("health-check %q: timeout %s must be shorter than interval %s", name, timeout, interval)
+	}
+
+	if check.IntervalJitter >= interval {
+		return fmt.Errorf("health-check %q: jitter %s must be shorter than interval %s", name, check.IntervalJitter, interval)
+	}
+
+	check.Lock()
+	check.Interval, check.Timeout = interval, timeout
+	check.Unlock()
+	select {
+	case check.reschedule <- struct{}{}:
+	default:
+		// the loop restarts its wait anyway
+	}
+	return nil
+}
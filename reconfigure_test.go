@@ -0,0 +1,60 @@
+package doctor
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNonPositiveIntervalsAreRejected(t *testing.T) {
+	health := NewDoctor(WithoutScheduler())
+	t.Cleanup(health.Stop)
+	handler := func(context.Context) error {
+		return nil
+	}
+	for _, config := range []struct{ interval, timeout time.Duration }{
+		{time.Second, -1},
+		{-time.Second, -2 * time.Second},
+		{-time.Second, 0},
+	} {
+		err := health.Investigate(&Check{Name: "db", Interval: config.interval, Timeout: config.timeout, Handler: handler})
+		if err == nil {
+			t.Fatalf("interval %s, timeout %s: registered", config.interval, config.timeout)
+		}
+	}
+
+	if err := health.Investigate(&Check{Name: "db", Interval: time.Second, Timeout: time.Millisecond, Handler: handler}); err != nil {
+		t.Fatal(err)
+	}
+	if err := health.Reconfigure("db", time.Second, -1); err == nil {
+		t.Fatal("negative timeout reconfigured")
+	}
+	if err := health.Reconfigure("db", -time.Second, -2*time.Second); err == nil {
+		t.Fatal("negative interval reconfigured")
+	}
+}
+
+func TestJitterBeyondIntervalIsRejected(t *testing.T) {
+	health := NewDoctor(WithoutScheduler())
+	t.Cleanup(health.Stop)
+	check := &Check{
+		Name:           "db",
+		Interval:       time.Second,
+		Timeout:        time.Millisecond,
+		IntervalJitter: time.Second,
+		Handler: func(context.Context) error {
+			return nil
+		},
+	}
+	if err := health.Investigate(check); err == nil {
+		t.Fatal("jitter of the whole interval registered")
+	}
+
+	check.IntervalJitter = 500 * time.Millisecond
+	if err := health.Investigate(check); err != nil {
+		t.Fatal(err)
+	}
+	if err := health.Reconfigure("db", 500*time.Millisecond, time.Millisecond); err == nil {
+		t.Fatal("interval reconfigured below the jitter")
+	}
+}
@@ -0,0 +1,33 @@
+package doctor
+
+// Reset removes all checks and groups and waits until their probe loops have exited, so a fresh
+// set can be registered. Unlike Stop, the doctor stays usable. Results of probes which were in
+// flight are discarded. The first round of probes starts over for StartupHandler and
+// WithFailOpen. An override of SetUnhealthy is kept.
+func (health *Doctor) Reset() {
+	checks := func() []*healthCheckStatus {
+		health.checks.Lock()
+		defer health.checks.Unlock()
+		checks := make([]*healthCheckStatus, 0, len(health.checks.items))
+		for _, check := range health.checks.items {
+			// cancel under the check lock, so a probe in flight cannot record its result anymore
+			check.Lock()
+			check.cancel()
+			check.Unlock()
+			checks = append(checks, check)
+		}
+		health.checks.items = make(map[string]*healthCheckStatus)
+		health.checks.groups = make(map[string]*healthGroup)
+		health.checks.next, health.checks.vacant = 0, nil
+		return checks
+	}()
+	for _, check := range checks {
+		<-check.exited
+	}
+
+	// the loops are gone, nothing sets a bit of the old checks anymore
+	health.status.reset()
+	health.optional.reset()
+	health.booted.Store(false)
+	health.aggregate()
+}
@@ -0,0 +1,55 @@
+package doctor
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLateResultAfterResetKeepsStatus(t *testing.T) {
+	health := NewDoctor(WithoutScheduler())
+	t.Cleanup(health.Stop)
+	if err := health.Group("g", 1); err != nil {
+		t.Fatal(err)
+	}
+	entered, release := make(chan struct{}), make(chan struct{})
+	err := health.Investigate(&Check{
+		Name:     "m",
+		Group:    "g",
+		Interval: time.Hour,
+		Handler: func(context.Context) error {
+			close(entered)
+			<-release
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := health.checks.items["m"]
+	go health.RunCheck(context.Background(), "m")
+	<-entered
+
+	health.Reset()
+	err = health.Investigate(&Check{
+		Name:     "n",
+		Interval: time.Hour,
+		Handler: func(context.Context) error {
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := health.RunCheck(context.Background(), "n"); err != nil {
+		t.Fatal(err)
+	}
+
+	// let the handler of the old member return, its slot is free once the result is applied
+	close(release)
+	old.probing <- struct{}{}
+
+	if !health.Healthy() {
+		t.Fatalf("unhealthy after a late result of a reset check, bits %v, reason %q", health.StatusBits(), health.Reason())
+	}
+}
@@ -0,0 +1,28 @@
+package doctor
+
+// ring is a fixed size buffer which overwrites its oldest item when full
+type ring[T any] struct {
+	items []T
+	next  int
+	full  bool
+}
+
+// newRing creates a ring with room for size items
+func newRing[T any](size int) *ring[T] {
+	return &ring[T]{items: make([]T, size)}
+}
+
+// add an item, overwriting the oldest one when the ring is full
+func (r *ring[T]) add(item T) {
+	r.items[r.next] = item
+	r.next = (r.next + 1) % len(r.items)
+	r.full = r.full || r.next == 0
+}
+
+// list returns a copy of the items, the oldest first
+func (r *ring[T]) list() []T {
+	if !r.full {
+		return append([]T(nil), r.items[:r.next]...)
+	}
+	return append(append([]T(nil), r.items[r.next:]...), r.items[:r.next]...)
+}
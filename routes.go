@@ -0,0 +1,32 @@
+package doctor
+
+import "net/http"
+
+// The conventional paths of the endpoints, as registered by Routes
+const (
+	PathHealth  = "/health"
+	PathHealthz = "/healthz"
+	PathLive    = "/livez"
+	PathReady   = "/readyz"
+	PathStartup = "/startupz"
+	PathProbe   = "/health/probe"
+	PathMetrics = "/metrics"
+)
+
+// paths are the conventional paths of the endpoints, which Middleware always lets pass
+var paths = []string{PathHealth, PathHealthz, PathLive, PathReady, PathStartup, PathProbe, PathMetrics}
+
+// Routes returns a mux with the endpoints of the doctor under their conventional paths. The
+// status page is served under both PathHealth and PathHealthz, add verbose=1 for every check.
+// Mount it under a prefix with http.StripPrefix.
+func (health *Doctor) Routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(PathHealth, health.Handler)
+	mux.HandleFunc(PathHealthz, health.Handler)
+	mux.HandleFunc(PathLive, health.LivenessHandler)
+	mux.HandleFunc(PathReady, health.ReadinessHandler)
+	mux.HandleFunc(PathStartup, health.StartupHandler)
+	mux.HandleFunc(PathProbe, health.ProbeHandler)
+	mux.HandleFunc(PathMetrics, health.MetricsHandler)
+	return mux
+}
@@ -0,0 +1,246 @@
+package doctor
+
+import (
+	"sort"
+	"time"
+)
+
+// CheckStatus holds the state of a single health-check
+type CheckStatus struct {
+
+	// The name of the check
+	Name string
+
+	// Whether the check is healthy
+	Healthy bool
+
+	// The severity of the check
+	Severity Severity
+
+	// Whether the check waits for its first probe after the initial delay
+	Starting bool
+
+	// Whether the check has no result yet, because its first probe did not finish
+	Unknown bool
+
+	// Whether the check is disabled, see Doctor.Disable
+	Disabled bool
+
+	// Whether the last probe was skipped because a dependency is unhealthy
+	Skipped bool
+
+	// Whether the last probe was canceled, e.g. by Stop, instead of finishing. Its result is not
+	// recorded, the state is the one before.
+	Interrupted bool
+
+	// The message of the last failure, empty when healthy
+	Message string
+
+	// The warning of the last probe, see Warning
+	Warning string
+
+	// The error of the last failure, nil when healthy
+	Err error
+
+	// The state of the circuit breaker, empty without one
+	Breaker string
+
+	// Whether the last probe did not finish within the timeout
+	TimedOut bool
+
+	// Whether the handler or the aspect raised the last failure, SourceHandler or SourceAspect.
+	// Empty when healthy.
+	Source string
+
+	// The details attached by the last probe, see CheckError
+	Detail map[string]any
+
+	// The code and the remedy of the last probe, see HealthDetailer. Empty without one.
+	Code string
+	Hint string
+
+	// When the last probe started
+	LastRun time.Time
+
+	// The duration of the last probe
+	Duration time.Duration
+
+	// The percentiles of the recent probe durations, nil without a latency window or before the
+	// first probe
+	Latency *Latency
+
+	// When the last successful probe started
+	LastSuccess time.Time
+
+	// When the last failed probe started
+	LastFailure time.Time
+
+	// The number of probes since the check was registered
+	Probes uint64
+
+	// The number of probes which failed with an error, not counting the timeouts
+	Errors uint64
+
+	// The number of probes which did not finish within the timeout
+	Timeouts uint64
+}
+
+// Snapshot is a consistent view of the health of the service and all its checks. It is a copy,
+// safe to keep and read without locking.
+type Snapshot struct {
+
+	// Whether the service is healthy
+	Healthy bool
+
+	// Whether non-critical checks fail
+	Degraded bool
+
+	// The reason of SetUnhealthy, empty when not forced unhealthy
+	Override string
+
+	// The state of all the checks, sorted by name
+	Checks []CheckStatus
+}
+
+// Snapshot returns the state of the service and all its checks, taken in a single pass. The
+// checks are locked for the duration of the copy, so no probe result is applied halfway. The
+// health policy is called once they are released, so it may query the doctor.
+func (health *Doctor) Snapshot() Snapshot {
+	snapshot := func() Snapshot {
+		health.checks.RLock()
+		defer health.checks.RUnlock()
+		checks := make([]*healthCheckStatus, 0, len(health.checks.items))
+		for _, hc := range health.checks.items {
+			hc.RLock()
+			checks = append(checks, hc)
+		}
+		snapshot := Snapshot{
+			Healthy:  health.status.clear(),
+			Degraded: !health.optional.clear(),
+			Checks:   make([]CheckStatus, 0, len(checks)),
+		}
+		for _, hc := range checks {
+			snapshot.Checks = append(snapshot.Checks, hc.copy())
+			hc.RUnlock()
+		}
+		return snapshot
+	}()
+	sort.Slice(snapshot.Checks, func(i, j int) bool {
+		return snapshot.Checks[i].Name < snapshot.Checks[j].Name
+	})
+	if health.policy != nil {
+		snapshot.Healthy = health.policy(snapshot.Checks)
+	}
+	if override := health.override.Load(); override != nil {
+		snapshot.Healthy, snapshot.Override = false, *override
+	}
+	return snapshot
+}
+
+// StatusBits returns a copy of the raw status of the critical checks and groups, one bit per
+// position, set while failing. Compare two copies to detect a change of the health with a
+// single comparison per word, e.g. on a hot path. A check keeps its position while registered;
+// the position of a removed check is reused by a later one, so the bits are only comparable
+// over a stable set of checks. Non-critical checks and members of a group are not included,
+// the words grow as checks are added.
+func (health *Doctor) StatusBits() []uint64 {
+	return health.AppendStatusBits(nil)
+}
+
+// AppendStatusBits appends the words of StatusBits to dst and returns the extended slice. Reuse
+// the slice, truncated with dst[:0], to compare without allocating on a hot path.
+func (health *Doctor) AppendStatusBits(dst []uint64) []uint64 {
+	for _, word := range health.status.load() {
+		dst = append(dst, word.Load())
+	}
+	return dst
+}
+
+// Names returns the names of the registered checks, sorted
+func (health *Doctor) Names() []string {
+	health.checks.RLock()
+	defer health.checks.RUnlock()
+	names := make([]string, 0, len(health.checks.items))
+	for name := range health.checks.items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsHealthy returns whether the named check is healthy, and whether it is registered at all
+func (health *Doctor) IsHealthy(name string) (healthy, ok bool) {
+	health.checks.RLock()
+	check, ok := health.checks.items[name]
+	health.checks.RUnlock()
+	if !ok {
+		return false, false
+	}
+	check.RLock()
+	defer check.RUnlock()
+	return check.healthy, true
+}
+
+// Status returns the state of all the health-checks, sorted by name
+func (health *Doctor) Status() []CheckStatus {
+	return health.checks.statuses(func(*healthCheckStatus) bool {
+		return true
+	})
+}
+
+// statuses returns the state of the matching checks, sorted by name
+func (checks *healthChecks) statuses(match func(*healthCheckStatus) bool) []CheckStatus {
+	checks.RLock()
+	defer checks.RUnlock()
+	statuses := make([]CheckStatus, 0, len(checks.items))
+	for _, hc := range checks.items {
+		if match(hc) {
+			statuses = append(statuses, hc.status())
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// status copies the state of the check
+func (hc *healthCheckStatus) status() CheckStatus {
+	hc.RLock()
+	defer hc.RUnlock()
+	return hc.copy()
+}
+
+// copy the state of the check, which must be locked
+func (hc *healthCheckStatus) copy() CheckStatus {
+	status := CheckStatus{
+		Name:        hc.Name,
+		Healthy:     hc.healthy,
+		Severity:    hc.Severity,
+		Starting:    hc.starting,
+		Unknown:     hc.unknown(),
+		Disabled:    hc.disabled,
+		Skipped:     hc.skipped,
+		Interrupted: hc.interrupted,
+		Message:     hc.msg,
+		Warning:     hc.warning,
+		Err:         hc.err,
+		Source:      hc.source,
+		TimedOut:    hc.timedOut,
+		Breaker:     hc.breaker,
+		Detail:      hc.detail,
+		Code:        hc.code,
+		Hint:        hc.hint,
+		LastRun:     hc.lastRun,
+		Duration:    hc.duration,
+		LastSuccess: hc.lastSuccess,
+		LastFailure: hc.lastFailure,
+		Probes:      hc.probes,
+		Errors:      hc.errors,
+		Timeouts:    hc.timeouts,
+	}
+	if hc.latencies != nil {
+		status.Latency = hc.latencies.percentiles()
+	}
+	return status
+}
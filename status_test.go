@@ -0,0 +1,43 @@
+package doctor
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSnapshotPolicyMayQueryDoctor(t *testing.T) {
+	var health *Doctor
+	registered := make(chan error, 1)
+	var queried atomic.Bool
+	health = NewDoctor(WithoutScheduler(), WithHealthPolicy(func(statuses []CheckStatus) bool {
+		if !queried.Swap(true) {
+			// a registration waits for the checks meanwhile, a new reader queues behind it
+			go func() {
+				registered <- health.Investigate(&Check{Name: "late", Interval: time.Hour, Handler: func(context.Context) error {
+					return nil
+				}})
+			}()
+			time.Sleep(20 * time.Millisecond)
+		}
+		_, _ = health.IsHealthy("db")
+		_ = health.Status()
+		return true
+	}))
+
+	done := make(chan Snapshot)
+	go func() {
+		done <- health.Snapshot()
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		// the doctor is stuck, stopping it would hang as well
+		t.Fatal("Snapshot deadlocked on a policy which queries the doctor")
+	}
+	health.Stop()
+	if err := <-registered; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,80 @@
+package doctor
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// fileMode lets a sidecar running as another user read the status file
+const fileMode = 0o644
+
+// statusFile is the file the state of the service is written to, see WithStatusFile
+type statusFile struct {
+	sync.Mutex
+	path string
+}
+
+// WithStatusFile writes the state of the service as JSON to the file at path whenever a check
+// changes, e.g. for a sidecar without HTTP access. The file holds the status, the failing checks
+// with their messages and the time of the change. It is replaced by a rename, so readers never
+// see a partial write. The file is readable by everyone, as the sidecar may run as another
+// user. Failed writes are logged.
+func WithStatusFile(path string) Option {
+	return func(health *Doctor) {
+		health.file = &statusFile{path: path}
+	}
+}
+
+// persist writes the current state to the status file. The state is taken under the lock of the
+// file, so the last write always holds the latest state.
+func (health *Doctor) persist() {
+	health.file.Lock()
+	defer health.file.Unlock()
+
+	var state = struct {
+		Status   string            `json:"status"`
+		Override string            `json:"override,omitempty"`
+		Errors   map[string]string `json:"errors,omitempty"`
+		Updated  time.Time         `json:"updated"`
+	}{Status: statusUp, Updated: health.clock.Now()}
+	switch {
+	case !health.Healthy():
+		state.Status, state.Errors = statusDown, health.checks.failing()
+	case health.Degraded():
+		state.Status, state.Errors = statusDegraded, health.checks.failing()
+	}
+	if override := health.override.Load(); override != nil {
+		state.Override = *override
+	}
+	if err := health.file.write(state); err != nil && health.logger != nil {
+		health.logger.Warn("health status file not written", "path", health.file.path, "error", err)
+	}
+}
+
+// write the JSON of v to a temporary file next to the status file and rename it over the latter
+func (f *statusFile) write(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(fileMode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
@@ -0,0 +1,56 @@
+package doctor
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Step probes the checks which are due on the clock of the doctor, one after another in the
+// order of their names, and returns their results by name. The next probe of each check is
+// scheduled like the probe loop does, with the initial delay, backoff, jitter and stabilization
+// window. Disabled checks, checks skipped for an unhealthy dependency and checks held back by
+// the breaker are left out of the results. The retry delays of a probe pass on a clock with a
+// Sleep method, as the fake clock of doctortest, since nothing advances the clock meanwhile.
+// It drives a doctor created WithoutScheduler, e.g. on
+// a fake clock in tests, see the doctortest package.
+func (health *Doctor) Step(ctx context.Context) map[string]error {
+	checks := health.checks.selection(nil)
+	sort.Slice(checks, func(i, j int) bool {
+		return checks[i].Name < checks[j].Name
+	})
+	results := make(map[string]error)
+	for _, hc := range checks {
+		if ctx.Err() != nil {
+			break
+		}
+		if hc.follow != nil || !hc.ready(health.clock.Now()) {
+			continue
+		}
+		ran, err := hc.check(health)
+		if ran {
+			results[hc.Name] = err
+		}
+		if hc.settled() {
+			hc.schedule(time.Time{})
+			continue
+		}
+		now := health.clock.Now()
+		hc.schedule(now.Add(hc.interval(now)))
+	}
+	return results
+}
+
+// schedule the next probe of Step, never again when zero
+func (hc *healthCheckStatus) schedule(due time.Time) {
+	hc.Lock()
+	defer hc.Unlock()
+	hc.due = due
+}
+
+// ready tells whether the check is due for Step
+func (hc *healthCheckStatus) ready(now time.Time) bool {
+	hc.RLock()
+	defer hc.RUnlock()
+	return !hc.disabled && !hc.due.IsZero() && !now.Before(hc.due) && hc.ctx.Err() == nil
+}
@@ -0,0 +1,80 @@
+package doctor_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decoomanj/doctor"
+	"github.com/decoomanj/doctor/doctortest"
+)
+
+func TestInvestigateRejectsDuplicateName(t *testing.T) {
+	d := doctortest.New()
+	defer d.Stop()
+	var first, second int
+	check := func(calls *int) *doctor.Check {
+		return &doctor.Check{
+			Name:     "db",
+			Interval: 10 * time.Second,
+			Timeout:  time.Second,
+			Handler: func(context.Context) error {
+				*calls++
+				return nil
+			},
+		}
+	}
+	if err := d.Investigate(check(&first)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Investigate(check(&second)); err == nil {
+		t.Fatal("duplicate check registered")
+	}
+
+	for i := 0; i < 3; i++ {
+		d.Tick(context.Background(), 10*time.Second)
+	}
+	if second != 0 {
+		t.Fatalf("rejected check probed %d times", second)
+	}
+	if first != 3 {
+		t.Fatalf("check probed %d times in 3 intervals, want a single schedule", first)
+	}
+	if n := len(d.Status()); n != 1 {
+		t.Fatalf("%d checks registered, want 1", n)
+	}
+}
+
+func TestPanickingHandlerIsUnhealthy(t *testing.T) {
+	d := doctortest.New()
+	defer d.Stop()
+	calls := 0
+	err := d.Investigate(&doctor.Check{
+		Name:     "broken",
+		Interval: 10 * time.Second,
+		Timeout:  time.Second,
+		Handler: func(context.Context) error {
+			calls++
+			panic("boom")
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := d.Tick(context.Background(), 0)
+	if err := results["broken"]; err == nil || !strings.Contains(err.Error(), "panic: boom") {
+		t.Fatalf("result %v does not carry the panic", err)
+	}
+	if d.Healthy() {
+		t.Fatal("panicking check reported healthy")
+	}
+	if status := d.Status()[0]; !strings.Contains(status.Message, "panic: boom") {
+		t.Fatalf("message %q does not carry the panic", status.Message)
+	}
+	d.Tick(context.Background(), 10*time.Second)
+	if calls != 2 {
+		t.Fatalf("check probed %d times, the schedule did not survive the panic", calls)
+	}
+}
@@ -0,0 +1,78 @@
+package doctor
+
+import "sync"
+
+type (
+	// watchers is a sync-list of functions to call on state changes
+	watchers struct {
+		sync.RWMutex
+		next  int
+		items map[int]func(name string, healthy bool)
+	}
+
+	// listeners is a sync-list of functions to call when the state of the service may have changed
+	listeners struct {
+		sync.RWMutex
+		next  int
+		items map[int]func()
+	}
+)
+
+// Watch registers a function which is called whenever a check becomes healthy or unhealthy. The
+// function must not block, it runs on the probe goroutine. The returned function unregisters it.
+func (health *Doctor) Watch(fn func(name string, healthy bool)) func() {
+	health.watchers.Lock()
+	defer health.watchers.Unlock()
+	id := health.watchers.next
+	health.watchers.next++
+	health.watchers.items[id] = fn
+	return func() {
+		health.watchers.Lock()
+		defer health.watchers.Unlock()
+		delete(health.watchers.items, id)
+	}
+}
+
+// notify all the watchers about a state change
+func (w *watchers) notify(name string, healthy bool) {
+	w.RLock()
+	fns := make([]func(string, bool), 0, len(w.items))
+	for _, fn := range w.items {
+		fns = append(fns, fn)
+	}
+	w.RUnlock()
+	for _, fn := range fns {
+		fn(name, healthy)
+	}
+}
+
+// OnChange registers a function which is called whenever the state of the service may have
+// changed: a check changed its state or succeeded for the first time, was removed, disabled or
+// enabled, the checks were reset, or the service was forced unhealthy or released. Unlike Watch, it does not tell what changed,
+// so the function re-evaluates what it needs. It must not block, it runs on the goroutine of the
+// change. The returned function unregisters it.
+func (health *Doctor) OnChange(fn func()) func() {
+	health.changes.Lock()
+	defer health.changes.Unlock()
+	id := health.changes.next
+	health.changes.next++
+	health.changes.items[id] = fn
+	return func() {
+		health.changes.Lock()
+		defer health.changes.Unlock()
+		delete(health.changes.items, id)
+	}
+}
+
+// notify all the listeners
+func (l *listeners) notify() {
+	l.RLock()
+	fns := make([]func(), 0, len(l.items))
+	for _, fn := range l.items {
+		fns = append(fns, fn)
+	}
+	l.RUnlock()
+	for _, fn := range fns {
+		fn()
+	}
+}
@@ -0,0 +1,51 @@
+package doctor
+
+import (
+	"fmt"
+	"time"
+)
+
+// watchdogTick is how often the watchdog looks for stale checks
+const watchdogTick = time.Second
+
+// watch marks probed checks as failing when their probe loop fell silent, until the doctor is
+// stopped
+func (health *Doctor) watch() {
+	defer health.wg.Done()
+	for {
+		select {
+		case <-health.halt.Done():
+			return
+		case <-health.clock.After(watchdogTick):
+		}
+		now := health.clock.Now()
+		for _, hc := range health.checks.selection(nil) {
+			if hc.follow == nil && hc.stale(now, health.staleness) {
+				hc.apply(health, hc.next(), fmt.Errorf("stale: not probed within %s", hc.deadline(health.staleness)), now, 0, "")
+			}
+		}
+	}
+}
+
+// stale tells whether the check was not probed within the multiple of its interval. The initial
+// delay and the jitter of the first probe are granted on top. Disabled and skipped checks,
+// checks with an open breaker and checks which are probed once are never stale.
+func (hc *healthCheckStatus) stale(now time.Time, multiplier float64) bool {
+	hc.RLock()
+	last := hc.lastRun
+	paused := hc.disabled || hc.skipped || hc.breaker == BreakerOpen || (hc.Once && !last.IsZero())
+	hc.RUnlock()
+	if paused {
+		return false
+	}
+	if first := hc.since.Add(hc.InitialDelay + hc.IntervalJitter); last.Before(first) {
+		last = first
+	}
+	return now.Sub(last) > hc.deadline(multiplier)
+}
+
+// deadline returns the time within which the check must be probed, the multiple of its current
+// interval including backoff and jitter
+func (hc *healthCheckStatus) deadline(multiplier float64) time.Duration {
+	return time.Duration(multiplier * float64(hc.backoff()+hc.IntervalJitter))
+}